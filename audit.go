@@ -0,0 +1,232 @@
+package dinero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// AnomalyEmpty flags a stored table with no rates at all.
+	AnomalyEmpty = "empty"
+	// AnomalyZero flags a rate of zero.
+	AnomalyZero = "zero"
+	// AnomalyNegative flags a negative rate.
+	AnomalyNegative = "negative"
+	// AnomalyJump flags a rate far out of line with its neighbouring days.
+	AnomalyJump = "jump"
+
+	defaultMaxJump = 10
+	// auditWindow is how many stored days either side of a day its values
+	// are compared with.
+	auditWindow = 3
+)
+
+// AuditOptions tunes what Audit considers implausible.
+type AuditOptions struct {
+	// MaxJump is the factor by which a rate may differ from the median of
+	// the same rate on the neighbouring stored days before it's flagged. Defaults to 10, an
+	// order of magnitude.
+	MaxJump float64
+}
+
+// Anomaly is an implausible value found in a stored rate table.
+type Anomaly struct {
+	Date   Date
+	Code   string
+	Rate   float64
+	Reason string
+}
+
+// AuditReport holds the problems found in a store for a base and date range.
+type AuditReport struct {
+	Base string
+	From Date
+	To   Date
+	// Missing lists the days with no stored rates.
+	Missing []Date
+	// Duplicates lists the days with more than one stored table.
+	Duplicates []Date
+	// Implausible lists the values that look wrong.
+	Implausible []Anomaly
+}
+
+// OK reports whether the audit found no problems.
+func (r *AuditReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Duplicates) == 0 && len(r.Implausible) == 0
+}
+
+// RepairDates returns the distinct days that need re-fetching, i.e. those
+// missing or holding implausible values, in ascending order.
+func (r *AuditReport) RepairDates() []Date {
+	seen := map[Date]bool{}
+	dates := []Date{}
+	add := func(date Date) {
+		if !seen[date] {
+			seen[date] = true
+			dates = append(dates, date)
+		}
+	}
+
+	for _, date := range r.Missing {
+		add(date)
+	}
+	for _, anomaly := range r.Implausible {
+		add(anomaly.Date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates
+}
+
+// Audit scans the rates held in store for base between from and to
+// (inclusive), reporting missing days, duplicate days and implausible values.
+func Audit(store RateStore, base string, from, to Date, opts *AuditOptions) (*AuditReport, error) {
+	if to.Before(from) {
+		return nil, errors.New("audit range ends before it starts")
+	}
+
+	maxJump := float64(defaultMaxJump)
+	if opts != nil && opts.MaxJump > 1 {
+		maxJump = opts.MaxJump
+	}
+
+	report := &AuditReport{
+		Base: base,
+		From: from,
+		To:   to,
+	}
+
+	dates, err := store.Dates(base)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the stored days in range.
+	counts := map[Date]int{}
+	for _, date := range dates {
+		if !date.Before(from) && !date.After(to) {
+			counts[date]++
+			if counts[date] == 2 {
+				report.Duplicates = append(report.Duplicates, date)
+			}
+		}
+	}
+
+	// Load the stored days in order, noting the missing ones.
+	stored := []storedDay{}
+	for date := from; !date.After(to); date = date.AddDays(1) {
+		if counts[date] == 0 {
+			report.Missing = append(report.Missing, date)
+			continue
+		}
+		rsp, err := store.Load(base, date)
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, storedDay{date, rsp})
+	}
+
+	// Check each value, and compare it with the neighbouring stored days.
+	series := map[string][]float64{}
+	for i, current := range stored {
+		if len(current.rsp.Rates) == 0 {
+			report.Implausible = append(report.Implausible, Anomaly{Date: current.date, Reason: AnomalyEmpty})
+			continue
+		}
+
+		for _, code := range sortedCodes(current.rsp.Rates) {
+			rate := current.rsp.Rates[code]
+			anomaly := Anomaly{Date: current.date, Code: code, Rate: rate}
+
+			switch {
+			case rate == 0:
+				anomaly.Reason = AnomalyZero
+			case rate < 0:
+				anomaly.Reason = AnomalyNegative
+			default:
+				// Compare against the median of the neighbouring days, so a
+				// single spike doesn't cast doubt on the days around it.
+				if _, ok := series[code]; !ok {
+					series[code] = rates(stored, code)
+				}
+				median, ok := neighbourMedian(series[code], i)
+				if !ok || (rate/median <= maxJump && median/rate <= maxJump) {
+					continue
+				}
+				anomaly.Reason = AnomalyJump
+			}
+			report.Implausible = append(report.Implausible, anomaly)
+		}
+	}
+
+	return report, nil
+}
+
+// Repair re-fetches every day the report found missing or implausible,
+// replacing what's held in the client's store. The service's base currency
+// must match the report's.
+func (s *HistoricalRatesService) Repair(report *AuditReport) ([]Date, error) {
+	if s.client.Store == nil {
+		return nil, errors.New("client has no store to repair")
+	}
+	if s.client.Offline {
+		return nil, ErrNotStored
+	}
+	if base := s.GetBaseCurrency(); base != report.Base {
+		return nil, fmt.Errorf("base currency %s doesn't match the report's base %s", base, report.Base)
+	}
+
+	repaired := []Date{}
+	for _, date := range report.RepairDates() {
+		s.client.Cache.expireOn(report.Base, date)
+		if err := s.fetch(context.Background(), date); err != nil {
+			return repaired, err
+		}
+		repaired = append(repaired, date)
+	}
+	return repaired, nil
+}
+
+// storedDay is a day's rates loaded from a store.
+type storedDay struct {
+	date Date
+	rsp  *RateResponse
+}
+
+// rates returns the rate for code on each stored day, or zero where it's
+// missing.
+func rates(days []storedDay, code string) []float64 {
+	values := make([]float64, len(days))
+	for i, day := range days {
+		values[i] = day.rsp.Rates[code]
+	}
+	return values
+}
+
+// neighbourMedian returns the median of the positive values within
+// auditWindow places either side of i, excluding i itself.
+func neighbourMedian(values []float64, i int) (float64, bool) {
+	neighbours := []float64{}
+	for j := i - auditWindow; j <= i+auditWindow; j++ {
+		if j != i && j >= 0 && j < len(values) && values[j] > 0 {
+			neighbours = append(neighbours, values[j])
+		}
+	}
+	if len(neighbours) == 0 {
+		return 0, false
+	}
+	sort.Float64s(neighbours)
+	return neighbours[(len(neighbours)-1)/2], true
+}
+
+func sortedCodes(rates map[string]float64) []string {
+	codes := make([]string, 0, len(rates))
+	for code := range rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
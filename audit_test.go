@@ -0,0 +1,84 @@
+package dinero
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestAudit will test finding missing, duplicate and implausible days in a store.
+func TestAudit(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// Day 3 is missing, day 2 is duplicated with a zero rate, and AUD spikes on day 4.
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	Expect(ioutil.WriteFile(path, []byte(`{"records": [
+		{"base": "USD", "date": "2021-01-01", "response": {"base": "USD", "rates": {"AUD": 1.35, "NZD": 1.45}}},
+		{"base": "USD", "date": "2021-01-02", "response": {"base": "USD", "rates": {"AUD": 1.36, "NZD": 1.44}}},
+		{"base": "USD", "date": "2021-01-02", "response": {"base": "USD", "rates": {"AUD": 1.36, "NZD": 0}}},
+		{"base": "USD", "date": "2021-01-04", "response": {"base": "USD", "rates": {"AUD": 14.2, "NZD": 1.46}}},
+		{"base": "USD", "date": "2021-01-05", "response": {"base": "USD", "rates": {"AUD": 1.37, "NZD": 1.47}}}
+	]}`), 0644)).Should(BeNil())
+
+	store, err := NewFileStore(path)
+	Expect(err).Should(BeNil())
+
+	from := NewDate(2021, 1, 1)
+	report, err := Audit(store, "USD", from, from.AddDays(4), nil)
+	Expect(err).Should(BeNil())
+	Expect(report.OK()).Should(BeFalse())
+	Expect(report.Missing).Should(Equal([]Date{from.AddDays(2)}))
+	Expect(report.Duplicates).Should(Equal([]Date{from.AddDays(1)}))
+	Expect(report.Implausible).Should(Equal([]Anomaly{
+		{Date: from.AddDays(1), Code: "NZD", Rate: 0, Reason: AnomalyZero},
+		{Date: from.AddDays(3), Code: "AUD", Rate: 14.2, Reason: AnomalyJump},
+	}))
+	Expect(report.RepairDates()).Should(Equal([]Date{
+		from.AddDays(1),
+		from.AddDays(2),
+		from.AddDays(3),
+	}))
+
+	// A looser threshold lets the spike through.
+	report, err = Audit(store, "USD", from, from.AddDays(4), &AuditOptions{MaxJump: 20})
+	Expect(err).Should(BeNil())
+	Expect(report.Implausible).Should(HaveLen(1))
+}
+
+// TestAudit_Repair will test re-fetching the days an audit flagged.
+func TestAudit_Repair(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "snapshot.json"))
+	Expect(err).Should(BeNil())
+
+	from := NewDate(2021, 1, 1)
+	Expect(store.Save("USD", from, &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": -1}})).Should(BeNil())
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"AUD": 1.35,
+	}))
+	client.Store = store
+
+	report, err := Audit(store, "USD", from, from.AddDays(1), nil)
+	Expect(err).Should(BeNil())
+	Expect(report.RepairDates()).Should(HaveLen(2))
+
+	repaired, err := client.HistoricalRates.Repair(report)
+	Expect(err).Should(BeNil())
+	Expect(repaired).Should(HaveLen(2))
+
+	report, err = Audit(store, "USD", from, from.AddDays(1), nil)
+	Expect(err).Should(BeNil())
+	Expect(report.OK()).Should(BeTrue())
+
+	// The report's base must match the service's.
+	client.HistoricalRates.SetBaseCurrency("AUD")
+	_, err = client.HistoricalRates.Repair(report)
+	Expect(err).ShouldNot(BeNil())
+}
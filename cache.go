@@ -1,62 +1,445 @@
 package dinero
 
 import (
+	"container/list"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
-
-	cache "github.com/patrickmn/go-cache"
 )
 
-// CacheService handles in-memory caching of our rates.
+// CacheService handles in-memory caching of our rates. Entries are evicted
+// least recently used first once the entry or memory limits are reached.
 type CacheService struct {
 	client *Client
-	store  *cache.Cache
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries from most to least recently used.
+	order *list.List
+	bytes int64
+
+	// failures holds the errors remembered for keys that failed to fetch.
+	failures map[string]*cacheFailure
+
+	maxEntries    int
+	maxBytes      int64
+	latestTTL     time.Duration
+	historicalTTL time.Duration
+	negativeTTL   time.Duration
+
+	hits         uint64
+	misses       uint64
+	evictions    uint64
+	negativeHits uint64
+}
+
+// CacheStats holds statistics about the cache.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// NegativeHits is the number of requests answered with a remembered
+	// error.
+	NegativeHits uint64
+	// Entries is the number of rate tables held.
+	Entries int
+	// Bytes is an estimate of the memory held by the rate tables.
+	Bytes int64
 }
 
-// NewCacheService creates a new handler for this service.
+// cacheEntry is a rate table held in the cache.
+type cacheEntry struct {
+	key     string
+	rsp     *RateResponse
+	size    int64
+	expires time.Time
+	// etag and lastModified are the validators OXR sent with the rates,
+	// used to revalidate them once expired.
+	etag         string
+	lastModified string
+}
+
+// cacheFailure is an error remembered for a key.
+type cacheFailure struct {
+	err     error
+	expires time.Time
+}
+
+// defaultMaxEntries is the number of rate tables held by default, a few years
+// of daily historical rates for one base.
+const defaultMaxEntries = 1000
+
+// NewCacheService creates a new handler for this service. Latest rates
+// expire after expiry, while historical rates (which never change) are kept
+// until evicted. At most 1000 rate tables are held, until changed with
+// SetMaxEntries.
 func NewCacheService(
 	client *Client,
-	store *cache.Cache,
+	expiry time.Duration,
 ) *CacheService {
 	return &CacheService{
-		client,
-		store,
+		client:     client,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+		failures:   map[string]*cacheFailure{},
+		maxEntries: defaultMaxEntries,
+		latestTTL:  expiry,
+	}
+}
+
+// SetMaxEntries will set the maximum number of rate tables held, evicting the
+// least recently used beyond it. It defaults to 1000; zero means no limit.
+func (s *CacheService) SetMaxEntries(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxEntries = n
+	s.evict()
+}
+
+// SetMaxBytes will set the (estimated) memory the rate tables held may use,
+// evicting the least recently used beyond it. Zero means no limit.
+func (s *CacheService) SetMaxBytes(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxBytes = n
+	s.evict()
+}
+
+// SetTTL will set how long latest and historical rates are held for. Rates
+// for the current day count as latest, as OXR may still update them. Zero
+// means they never expire. Entries already held keep their expiry.
+func (s *CacheService) SetTTL(latest, historical time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latestTTL = latest
+	s.historicalTTL = historical
+}
+
+// SetNegativeTTL will set how long errors from OXR that will never succeed
+// on retry (e.g. not_available for a date, or invalid_base) are remembered,
+// and returned again without calling OXR. Server errors, rate limiting and
+// network errors are never remembered. Zero, the default, turns this off.
+func (s *CacheService) SetNegativeTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.negativeTTL = ttl
+	if ttl <= 0 {
+		s.failures = map[string]*cacheFailure{}
+	}
+}
+
+// Stats will return statistics about the cache.
+func (s *CacheService) Stats() CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return CacheStats{
+		Hits:         s.hits,
+		Misses:       s.misses,
+		Evictions:    s.evictions,
+		NegativeHits: s.negativeHits,
+		Entries:      s.order.Len(),
+		Bytes:        s.bytes,
 	}
 }
 
 // Get will return our in-memory stored currency/rates.
 func (s *CacheService) Get(base string, date time.Time) (*RateResponse, bool) {
-	if x, found := s.store.Get(getCacheKey(base, date)); found {
-		return x.(*RateResponse), found
-	}
-	return nil, false
+	return s.getOn(context.Background(), base, s.client.dateOf(date))
 }
 
 // Store will store our currency/rates in-memory.
 func (s *CacheService) Store(rsp *RateResponse, date time.Time) {
-	// Set a stored timestamp.
-	rsp.Timestamp = time.Now().Unix()
-
-	s.store.Set(
-		getCacheKey(rsp.Base, date),
-		rsp,
-		cache.DefaultExpiration,
-	)
+	s.storeOn(rsp, s.client.dateOf(date), nil)
 }
 
 // IsExpired checks whether the rate stored is expired.
 func (s *CacheService) IsExpired(base string, date time.Time) bool {
-	if _, found := s.store.Get(getCacheKey(base, date)); found {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.lookup(getCacheKey(base, s.client.dateOf(date)))
+	return !found
+}
+
+// Expire will expire the cache for a given base currency.
+func (s *CacheService) Expire(base string, date time.Time) {
+	s.expireOn(base, s.client.dateOf(date))
+}
+
+func (s *CacheService) getOn(ctx context.Context, base string, date Date) (*RateResponse, bool) {
+	_, span := s.client.startSpan(ctx, "dinero.Cache.Get",
+		baseAttribute(base),
+		Attribute{Key: AttributeDate, Value: date.String()},
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, found := s.lookup(getCacheKey(base, date))
+	s.client.observeCache(s.kind(date), found)
+	span.SetAttributes(Attribute{Key: AttributeCacheHit, Value: found})
+	s.client.log(LevelDebug, "cache lookup",
+		Field{Key: "key", Value: getCacheKey(base, date)},
+		Field{Key: "hit", Value: found},
+	)
+	if !found {
+		s.misses++
+		return nil, false
+	}
+	s.hits++
+	return entry.rsp, true
+}
+
+// storeOn stores rsp for date, along with any validators in the response
+// header it came with.
+func (s *CacheService) storeOn(rsp *RateResponse, date Date, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &cacheEntry{
+		key:          getCacheKey(rsp.Base, date),
+		rsp:          rsp,
+		size:         rsp.size(),
+		expires:      s.expiry(rsp, date),
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+	}
+
+	if element, ok := s.entries[entry.key]; ok {
+		s.remove(element)
+	}
+	if s.kind(date) == CacheLatest {
+		s.client.observeLatest(rsp)
+	}
+
+	delete(s.failures, entry.key)
+	s.entries[entry.key] = s.order.PushFront(entry)
+	s.bytes += entry.size
+	s.sweep()
+	s.evict()
+}
+
+func (s *CacheService) expireOn(base string, date Date) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := getCacheKey(base, date)
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+	delete(s.failures, key)
+}
+
+// validate adds conditional headers to req for the rates held for base on
+// date, even if they've expired, so OXR can answer that they haven't changed.
+// It reports whether any were added.
+func (s *CacheService) validate(req *http.Request, base string, date Date) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[getCacheKey(base, date)]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*cacheEntry)
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+	return isConditional(req)
+}
+
+// revalidate renews the lifetime of the rates held for base on date, after
+// OXR has confirmed they haven't changed. It reports whether they were still
+// held.
+func (s *CacheService) revalidate(base string, date Date) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[getCacheKey(base, date)]
+	if !ok {
 		return false
 	}
+	entry := element.Value.(*cacheEntry)
+	entry.expires = s.expiry(entry.rsp, date)
+	s.order.MoveToFront(element)
+	s.client.log(LevelDebug, "cache revalidated", Field{Key: "key", Value: entry.key})
 	return true
 }
 
-// Expire will expire the cache for a given base currency.
-func (s *CacheService) Expire(base string, date time.Time) {
-	s.store.Delete(getCacheKey(base, date))
+// isConditional reports whether req asks OXR to answer 304 Not Modified if
+// what's held is current.
+func isConditional(req *http.Request) bool {
+	return req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+}
+
+// kind returns whether rates for date count as latest or historical. Rates
+// for the current day count as latest, as OXR may still update them.
+func (s *CacheService) kind(date Date) string {
+	if date.Before(s.client.today()) {
+		return CacheHistorical
+	}
+	return CacheLatest
+}
+
+// expiry returns when rsp, held for date, should expire, or the zero time if
+// it never should. The caller must hold s.mu.
+func (s *CacheService) expiry(rsp *RateResponse, date Date) time.Time {
+	ttl := s.historicalTTL
+	if rsp.Date == "" || s.kind(date) == CacheLatest {
+		ttl = s.latestTTL
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// failure returns the error remembered for fetching base's rates on date, or
+// nil if there is none.
+func (s *CacheService) failure(base string, date Date) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := getCacheKey(base, date)
+	failure, ok := s.failures[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(failure.expires) {
+		delete(s.failures, key)
+		return nil
+	}
+	s.negativeHits++
+	s.client.log(LevelDebug, "cache negative hit",
+		Field{Key: "key", Value: key},
+		Field{Key: "error", Value: failure.err},
+	)
+	return failure.err
+}
+
+// fail remembers err for fetching base's rates on date, if negative caching
+// is on and err will never succeed on retry.
+func (s *CacheService) fail(base string, date Date, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.negativeTTL <= 0 || !isPermanent(err) {
+		return
+	}
+	s.failures[getCacheKey(base, date)] = &cacheFailure{
+		err:     err,
+		expires: time.Now().Add(s.negativeTTL),
+	}
+}
+
+// lookup returns the unexpired entry for key, marking it as recently used.
+// Expired entries are dropped, unless they're retained. The caller must hold
+// s.mu.
+func (s *CacheService) lookup(key string) (*cacheEntry, bool) {
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if entry.expired(time.Now()) {
+		if !s.retain(entry) {
+			s.remove(element)
+		}
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return entry, true
+}
+
+// expired reports whether the entry has expired by now.
+func (e *cacheEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// retain reports whether an expired entry is still of use, and so kept until
+// evicted or replaced: to be revalidated with its validators, or served
+// stale by a QuotaGuard in QuotaStale mode.
+func (s *CacheService) retain(entry *cacheEntry) bool {
+	return entry.etag != "" || entry.lastModified != "" || s.client.Quota.servesStale()
+}
+
+// sweep drops the expired entries that aren't retained, and the expired
+// errors remembered. The caller must hold s.mu.
+func (s *CacheService) sweep() {
+	now := time.Now()
+	for element := s.order.Back(); element != nil; {
+		prev := element.Prev()
+		if entry := element.Value.(*cacheEntry); entry.expired(now) && !s.retain(entry) {
+			s.remove(element)
+		}
+		element = prev
+	}
+	for key, failure := range s.failures {
+		if now.After(failure.expires) {
+			delete(s.failures, key)
+		}
+	}
+}
+
+// stale returns the rates held for base on date, even if they've expired.
+func (s *CacheService) stale(base string, date Date) (*RateResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[getCacheKey(base, date)]
+	if !ok {
+		return nil, false
+	}
+	return element.Value.(*cacheEntry).rsp, true
+}
+
+// evict drops the least recently used entries until the cache is within its
+// limits, always keeping the most recent. The caller must hold s.mu.
+func (s *CacheService) evict() {
+	for s.order.Len() > 1 &&
+		((s.maxEntries > 0 && s.order.Len() > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)) {
+		entry := s.remove(s.order.Back())
+		s.evictions++
+		s.client.log(LevelDebug, "cache evicted", Field{Key: "key", Value: entry.key})
+	}
+}
+
+// remove drops an entry, returning it. The caller must hold s.mu.
+func (s *CacheService) remove(element *list.Element) *cacheEntry {
+	entry := s.order.Remove(element).(*cacheEntry)
+	delete(s.entries, entry.key)
+	s.bytes -= entry.size
+	return entry
+}
+
+// isPermanent reports whether err is an error response from OXR that a retry
+// won't change, i.e. a client error other than rate limiting. Errors with the
+// app ID aren't, as the key may be rotated.
+func isPermanent(err error) bool {
+	var rsp *ErrorResponse
+	if !errors.As(err, &rsp) || rsp.Response == nil || isInvalidKey(err) {
+		return false
+	}
+	switch code := rsp.Response.StatusCode; {
+	case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout:
+		return false
+	default:
+		return code >= 400 && code < 500
+	}
 }
 
-func getCacheKey(base string, date time.Time) string {
-	return fmt.Sprintf("%s_%s", base, date.Format("2006-01-02"))
+func getCacheKey(base string, date Date) string {
+	return fmt.Sprintf("%s_%s", base, date)
 }
@@ -2,6 +2,7 @@ package dinero
 
 import (
 	"encoding/json"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -12,10 +13,10 @@ import (
 // TestCache will test that our in-memory cache of forex results is working.
 func TestCache(t *testing.T) {
 	// Register the test.
-	NewWithT(t)
+	RegisterTestingT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, "AUD", 1*time.Minute)
+	client := newClient(t, "AUD")
 
 	// Get latest forex rates.
 	response1, err := client.Rates.List()
@@ -32,8 +33,11 @@ func TestCache(t *testing.T) {
 		t.Fatalf("Expected response when fetching from cache for base currency AUD, got: %v", response2)
 	}
 
+	// The cache holds the rates as OXR returned them; List sets the euro pegs
+	// on top, but leaves out the legacy euro-zone currencies, e.g. DEM.
+	Expect(response1.Rates).ShouldNot(HaveKey("DEM"))
 	first, _ := json.Marshal(response1)
-	second, _ := json.Marshal(response2)
+	second, _ := json.Marshal(client.decorate(response2, client.today()))
 	Expect(first).To(MatchJSON(second))
 
 	// Expire the cache
@@ -45,3 +49,167 @@ func TestCache(t *testing.T) {
 	// Should be nothing.
 	Expect(response2).Should(BeNil())
 }
+
+// TestCache_Limits will test LRU eviction, per-type expiry and cache statistics.
+func TestCache_Limits(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	requests := 0
+	handler := ratesHandler(map[string]float64{"AUD": 1.3, "NZD": 1.4})
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		handler.ServeHTTP(w, r)
+	}))
+	Expect(client.Cache.maxEntries).Should(Equal(defaultMaxEntries))
+	client.Cache.SetMaxEntries(2)
+
+	first := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 3; day++ {
+		_, err := client.HistoricalRates.List(first.AddDate(0, 0, day))
+		Expect(err).Should(BeNil())
+	}
+
+	stats := client.Cache.Stats()
+	Expect(stats.Entries).Should(Equal(2))
+	Expect(stats.Evictions).Should(Equal(uint64(1)))
+	Expect(stats.Bytes).Should(BeNumerically(">", 0))
+
+	// The least recently used day was evicted, the others are served from
+	// the cache.
+	Expect(client.Cache.IsExpired("USD", first)).Should(BeTrue())
+	_, err := client.HistoricalRates.List(first.AddDate(0, 0, 2))
+	Expect(err).Should(BeNil())
+	Expect(requests).Should(Equal(3))
+	Expect(client.Cache.Stats().Hits).Should(BeNumerically(">", stats.Hits))
+
+	// Latest rates expire, historical rates don't. Expired rates without
+	// validators to revalidate them with are dropped.
+	client.Cache.SetMaxEntries(0)
+	client.Cache.SetTTL(time.Millisecond, 0)
+	_, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(client.Cache.Stats().Entries).Should(Equal(3))
+	time.Sleep(5 * time.Millisecond)
+	Expect(client.Cache.IsExpired("USD", time.Now())).Should(BeTrue())
+	Expect(client.Cache.IsExpired("USD", first.AddDate(0, 0, 2))).Should(BeFalse())
+	Expect(client.Cache.Stats().Entries).Should(Equal(2))
+
+	// A memory budget evicts down to what fits.
+	client.Cache.SetMaxBytes(stats.Bytes / 2)
+	Expect(client.Cache.Stats().Entries).Should(Equal(1))
+}
+
+// TestCache_NegativeTTL will test remembering errors that won't succeed on retry.
+func TestCache_NegativeTTL(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	requests := map[string]int{}
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		if strings.Contains(r.URL.Path, "2021-01-01") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":true,"status":400,"message":"not_available","description":"Historical rates for the requested date are not available"}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":true,"status":500,"message":"server_error","description":"Something went wrong"}`))
+	}))
+	client.Cache.SetNegativeTTL(1 * time.Minute)
+
+	unavailable := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := client.HistoricalRates.List(unavailable)
+	Expect(err).ShouldNot(BeNil())
+	_, err2 := client.HistoricalRates.List(unavailable)
+	Expect(err2).Should(Equal(err))
+	Expect(requests["/api/historical/2021-01-01.json"]).Should(Equal(1))
+	Expect(client.Cache.Stats().NegativeHits).Should(Equal(uint64(1)))
+
+	// Server errors are always retried.
+	failing := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	_, err = client.HistoricalRates.List(failing)
+	Expect(err).ShouldNot(BeNil())
+	_, err = client.HistoricalRates.List(failing)
+	Expect(err).ShouldNot(BeNil())
+	Expect(requests["/api/historical/2021-01-02.json"]).Should(Equal(2))
+
+	// Expiring the key forgets the error.
+	client.Cache.Expire("USD", unavailable)
+	_, err = client.HistoricalRates.List(unavailable)
+	Expect(err).ShouldNot(BeNil())
+	Expect(requests["/api/historical/2021-01-01.json"]).Should(Equal(2))
+}
+
+// TestCache_Revalidate will test refreshing expired rates with conditional requests.
+func TestCache_Revalidate(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	var downloads, notModified int
+	handler := ratesHandler(map[string]float64{"AUD": 1.3})
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		handler.ServeHTTP(w, r)
+	}))
+	client.Cache.SetTTL(50*time.Millisecond, 0)
+
+	rate, err := client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.3))
+
+	// Once expired, the rates are revalidated rather than downloaded again.
+	time.Sleep(60 * time.Millisecond)
+	Expect(client.Cache.IsExpired("USD", time.Now())).Should(BeTrue())
+	rate, err = client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.3))
+	Expect(downloads).Should(Equal(1))
+	Expect(notModified).Should(Equal(1))
+
+	// Expiring the rates drops them, validators and all.
+	client.Cache.Expire("USD", time.Now())
+	_, err = client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(downloads).Should(Equal(2))
+}
+
+// TestCache_UnexpectedNotModified will test 304 responses with nothing held to revalidate.
+func TestCache_UnexpectedNotModified(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	// Unconditional requests can't be answered with 304.
+	_, err := client.Rates.List()
+	Expect(err).ShouldNot(BeNil())
+	_, err = client.HistoricalRates.ListOn(Date{Year: 2021, Month: time.January, Day: 1})
+	Expect(err).ShouldNot(BeNil())
+	_, err = client.Currencies.List()
+	Expect(err).ShouldNot(BeNil())
+	_, err = client.Usage.Get()
+	Expect(err).ShouldNot(BeNil())
+
+	// Nor can conditional ones once the rates have gone.
+	client.Cache.SetTTL(time.Millisecond, 0)
+	client.Cache.storeOn(&RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.3}}, client.today(), http.Header{"Etag": []string{`"v1"`}})
+	time.Sleep(5 * time.Millisecond)
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// Evicted while the request is in flight.
+			client.Cache.Expire("USD", time.Now())
+			return httptestResponse(req, http.StatusNotModified, ""), nil
+		})
+	})
+	_, err = client.Rates.List()
+	Expect(err).Should(Equal(ErrNothingToRevalidate))
+}
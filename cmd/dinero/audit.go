@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattevans/dinero"
+)
+
+// runAudit scans a rate store for missing days, duplicate days and
+// implausible values, optionally re-fetching the days that need it.
+func runAudit(env *environment, args []string) error {
+	fs := env.flags()
+	env.snapshotFlags(fs, false)
+	base := fs.String("base", "", "base currency to audit (defaults to the configured base, or USD)")
+	fromFlag := fs.String("from", "", "first date of the range (YYYY-MM-DD)")
+	toFlag := fs.String("to", "today", "last date of the range (YYYY-MM-DD or today)")
+	dir := fs.String("store", "", "directory of the rate store to audit (defaults to the snapshot file)")
+	maxJump := fs.Float64("max-jump", 10, "factor a rate may differ from its neighbouring days by before it's flagged")
+	repair := fs.Bool("repair", false, "re-fetch the missing and implausible days")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("unexpected arguments")
+	}
+
+	from, err := dinero.ParseDate(*fromFlag)
+	if err != nil {
+		return err
+	}
+	to := today()
+	if *toFlag != "today" {
+		if to, err = dinero.ParseDate(*toFlag); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := env.config()
+	if err != nil {
+		return err
+	}
+	if *base == "" {
+		*base = cfg.Base
+	}
+	if *base == "" {
+		*base = "USD"
+	}
+
+	var (
+		store     dinero.RateStore
+		fileStore *dinero.FileStore
+	)
+	if *dir != "" {
+		if store, err = dinero.NewDirStore(*dir); err != nil {
+			return err
+		}
+	} else {
+		if fileStore, err = env.store(cfg); err != nil {
+			return err
+		}
+		store = fileStore
+	}
+
+	report, err := dinero.Audit(store, *base, from, to, &dinero.AuditOptions{MaxJump: *maxJump})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"Date", "Issue", "Code", "Rate"}}
+	for _, date := range report.Missing {
+		t.add(date.String(), "missing", "", "")
+	}
+	for _, date := range report.Duplicates {
+		t.add(date.String(), "duplicate", "", "")
+	}
+	for _, anomaly := range report.Implausible {
+		t.add(anomaly.Date.String(), anomaly.Reason, anomaly.Code, anomaly.Rate)
+	}
+	if err := t.write(env.stdout, env.format); err != nil {
+		return err
+	}
+
+	if report.OK() {
+		return nil
+	}
+	if !*repair {
+		return fmt.Errorf("found %d issues", len(t.rows))
+	}
+
+	client, err := env.client(*base)
+	if err != nil {
+		return err
+	}
+	client.Store = store
+
+	var repaired []dinero.Date
+	repairAll := func() error {
+		repaired, err = client.HistoricalRates.Repair(report)
+		return err
+	}
+	// Write the snapshot once, rather than for every day repaired.
+	if fileStore != nil {
+		err = fileStore.Batch(repairAll)
+	} else {
+		err = repairAll()
+	}
+	for _, date := range repaired {
+		fmt.Fprintf(env.stderr, "repaired %s\n", date.String())
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattevans/dinero"
+	. "github.com/onsi/gomega"
+)
+
+// TestCLI_Audit will test reporting and repairing problems in a rate store.
+func TestCLI_Audit(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	dir := filepath.Join(t.TempDir(), "rates")
+	store, err := dinero.NewDirStore(dir)
+	Expect(err).Should(BeNil())
+
+	from := dinero.NewDate(2021, 1, 1)
+	Expect(store.Save("USD", from, &dinero.RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.25}})).Should(BeNil())
+	Expect(store.Save("USD", from.AddDays(2), &dinero.RateResponse{Base: "USD", Rates: map[string]float64{"AUD": -1}})).Should(BeNil())
+
+	getenv := newTestEnv(t)
+	args := []string{"audit", "--store", dir, "--from", "2021-01-01", "--to", "2021-01-03", "--format", "csv"}
+
+	code, stdout, stderr := runTest(getenv, args...)
+	Expect(code).Should(Equal(1))
+	Expect(stdout).Should(Equal("Date,Issue,Code,Rate\n2021-01-02,missing,,\n2021-01-03,negative,AUD,-1\n"))
+	Expect(stderr).Should(ContainSubstring("found 2 issues"))
+
+	code, _, stderr = runTest(getenv, append(args, "--repair")...)
+	Expect(code).Should(Equal(0), stderr)
+	Expect(stderr).Should(Equal("repaired 2021-01-02\nrepaired 2021-01-03\n"))
+
+	code, stdout, _ = runTest(getenv, args...)
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(Equal("Date,Issue,Code,Rate\n"))
+
+	// The base defaults to the configured one, which has nothing stored.
+	config := filepath.Join(t.TempDir(), "config.json")
+	Expect(ioutil.WriteFile(config, []byte(`{"base": "AUD"}`), 0644)).Should(BeNil())
+	withConfig := func(key string) string {
+		if key == configEnv {
+			return config
+		}
+		return getenv(key)
+	}
+	code, stdout, _ = runTest(withConfig, args...)
+	Expect(code).Should(Equal(1))
+	Expect(stdout).Should(Equal("Date,Issue,Code,Rate\n2021-01-01,missing,,\n2021-01-02,missing,,\n2021-01-03,missing,,\n"))
+}
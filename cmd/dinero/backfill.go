@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mattevans/dinero"
+)
+
+// checkpoint records the progress of a backfill, so an interrupted run can
+// resume where it left off.
+type checkpoint struct {
+	Base string `json:"base"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Next is the earliest date in the range not yet known to be stored.
+	Next string `json:"next"`
+}
+
+// runBackfill fetches the historical rates for every day in a range that
+// isn't already in a directory store, staying within a share of the monthly
+// request quota.
+func runBackfill(env *environment, args []string) error {
+	fs := env.flags()
+	base := fs.String("base", "", "base currency (defaults to the configured base, or USD)")
+	fromFlag := fs.String("from", "", "first date of the range (YYYY-MM-DD)")
+	toFlag := fs.String("to", "yesterday", "last date of the range (YYYY-MM-DD or yesterday)")
+	dir := fs.String("store", "", "directory of the rate store to fill")
+	workers := fs.Int("workers", 4, "number of concurrent requests")
+	budget := fs.Float64("budget", 0.8, "share of the monthly request quota the run may use up to")
+	checkpointPath := fs.String("checkpoint", "", "path to the checkpoint file (defaults to one in the store)")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("unexpected arguments")
+	}
+	if *dir == "" {
+		return errors.New("--store must be passed")
+	}
+	if *workers < 1 {
+		return errors.New("--workers must be at least 1")
+	}
+	if *budget <= 0 || *budget > 1 {
+		return errors.New("--budget must be between 0 and 1")
+	}
+
+	from, err := dinero.ParseDate(*fromFlag)
+	if err != nil {
+		return err
+	}
+	to := today().AddDays(-1)
+	if *toFlag != "yesterday" {
+		if to, err = dinero.ParseDate(*toFlag); err != nil {
+			return err
+		}
+	}
+	if to.Before(from) {
+		return errors.New("--to must not be before --from")
+	}
+	// Today's rates are still being updated, so would be stored too early.
+	if !to.Before(today()) {
+		return errors.New("--to must be before today, whose rates aren't final")
+	}
+
+	client, err := env.client(*base)
+	if err != nil {
+		return err
+	}
+	if client.HistoricalRates.GetBaseCurrency() == "" {
+		client.HistoricalRates.SetBaseCurrency("USD")
+	}
+	baseCode := client.HistoricalRates.GetBaseCurrency()
+
+	store, err := dinero.NewDirStore(*dir)
+	if err != nil {
+		return err
+	}
+	client.Store = store
+
+	// Resume from the checkpoint if it's for the same backfill.
+	if *checkpointPath == "" {
+		*checkpointPath = filepath.Join(*dir, ".backfill-"+baseCode+".json")
+	}
+	cp := &checkpoint{
+		Base: baseCode,
+		From: from.String(),
+		To:   to.String(),
+		Next: from.String(),
+	}
+	if saved, err := loadCheckpoint(*checkpointPath); err != nil {
+		return err
+	} else if saved != nil && saved.Base == cp.Base && saved.From == cp.From && saved.To == cp.To {
+		cp.Next = saved.Next
+		fmt.Fprintf(env.stderr, "resuming from %s\n", cp.Next)
+	}
+	next, err := dinero.ParseDate(cp.Next)
+	if err != nil {
+		return err
+	}
+
+	// Work out which days are missing.
+	dates, err := store.Dates(baseCode)
+	if err != nil {
+		return err
+	}
+	stored := map[string]bool{}
+	for _, date := range dates {
+		stored[date.String()] = true
+	}
+	for !next.After(to) && stored[next.String()] {
+		next = next.AddDays(1)
+	}
+	missing := []dinero.Date{}
+	for date := next; !date.After(to); date = date.AddDays(1) {
+		if !stored[date.String()] {
+			missing = append(missing, date)
+		}
+	}
+
+	// Nothing to fetch, so no need to check the budget.
+	allowed := 0
+	if len(missing) > 0 {
+		if allowed, err = requestBudget(client, *budget, len(missing)); err != nil {
+			return err
+		}
+	}
+
+	// Fetch the missing days, advancing the checkpoint past every day that's
+	// been stored without a gap before it.
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		fetched  int
+		stop     = make(chan struct{})
+		jobs     = make(chan dinero.Date)
+	)
+	advance := func(date dinero.Date) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fetched++
+		stored[date.String()] = true
+		fmt.Fprintf(env.stderr, "fetched %s (%d/%d)\n", date.String(), fetched, len(missing))
+
+		for !next.After(to) && stored[next.String()] {
+			next = next.AddDays(1)
+		}
+		cp.Next = next.String()
+		return saveCheckpoint(*checkpointPath, cp)
+	}
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+			close(stop)
+		}
+	}
+
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for date := range jobs {
+				if _, err := client.HistoricalRates.ListOn(date); err != nil {
+					fail(fmt.Errorf("fetching %s: %w", date.String(), err))
+					return
+				}
+				if err := advance(date); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for _, date := range missing {
+		if dispatched >= allowed {
+			break
+		}
+		select {
+		case jobs <- date:
+			dispatched++
+		case <-stop:
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	status := "complete"
+	switch {
+	case firstErr != nil:
+		status = "failed"
+	case next.After(to):
+		if err := os.Remove(*checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	default:
+		status = "budget reached"
+	}
+
+	t := &table{header: []string{"Base", "From", "To", "Fetched", "Remaining", "Status"}}
+	t.add(baseCode, cp.From, cp.To, fetched, len(missing)-fetched, status)
+	if err := t.write(env.stdout, env.format); err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// requestBudget returns how many requests may be made before usage reaches
+// the given share of the monthly quota, capped at needed.
+func requestBudget(client *dinero.Client, share float64, needed int) (int, error) {
+	rsp, err := client.Usage.Get()
+	if err != nil {
+		return 0, fmt.Errorf("checking usage: %w", err)
+	}
+
+	usage := rsp.Data.Usage
+	if usage.Unlimited() {
+		return needed, nil
+	}
+
+	allowed := int(float64(usage.RequestsQuota)*share) - int(usage.Requests)
+	if allowed <= 0 {
+		return 0, fmt.Errorf("quota budget already used: %d of %d requests made", usage.Requests, usage.RequestsQuota)
+	}
+	if allowed > needed {
+		allowed = needed
+	}
+	return allowed, nil
+}
+
+// loadCheckpoint reads the checkpoint at path, returning nil if there isn't one.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint writes cp to path.
+func saveCheckpoint(path string, cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// today returns the current date in UTC, the day OXR's latest rates are for.
+func today() dinero.Date {
+	return dinero.DateOf(time.Now().UTC())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestCLI_Backfill will test a backfill stops at the quota budget and resumes from its checkpoint.
+func TestCLI_Backfill(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	dir := filepath.Join(t.TempDir(), "rates")
+	getenv := newTestEnv(t)
+
+	// The fake plan has a quota of 1000 requests, so this allows 3.
+	args := []string{"backfill", "--from", "2021-01-01", "--to", "2021-01-05", "--store", dir, "--budget", "0.003", "--workers", "2", "--format", "csv"}
+
+	code, stdout, stderr := runTest(getenv, args...)
+	Expect(code).Should(Equal(0), stderr)
+	Expect(stdout).Should(Equal("Base,From,To,Fetched,Remaining,Status\nUSD,2021-01-01,2021-01-05,3,2,budget reached\n"))
+
+	files, err := filepath.Glob(filepath.Join(dir, "USD", "*.json"))
+	Expect(err).Should(BeNil())
+	Expect(files).Should(HaveLen(3))
+	Expect(filepath.Join(dir, ".backfill-USD.json")).Should(BeAnExistingFile())
+
+	code, stdout, stderr = runTest(getenv, args...)
+	Expect(code).Should(Equal(0), stderr)
+	Expect(stderr).Should(ContainSubstring("resuming from"))
+	Expect(stdout).Should(Equal("Base,From,To,Fetched,Remaining,Status\nUSD,2021-01-01,2021-01-05,2,0,complete\n"))
+
+	files, err = filepath.Glob(filepath.Join(dir, "USD", "*.json"))
+	Expect(err).Should(BeNil())
+	Expect(files).Should(HaveLen(5))
+
+	_, err = os.Stat(filepath.Join(dir, ".backfill-USD.json"))
+	Expect(os.IsNotExist(err)).Should(BeTrue())
+
+	// With nothing missing, the spent budget doesn't matter.
+	code, stdout, stderr = runTest(getenv, args...)
+	Expect(code).Should(Equal(0), stderr)
+	Expect(stdout).Should(Equal("Base,From,To,Fetched,Remaining,Status\nUSD,2021-01-01,2021-01-05,0,0,complete\n"))
+
+	// Today's rates aren't final, so aren't backfilled.
+	code, _, stderr = runTest(getenv, "backfill", "--from", "2021-01-01", "--to", today().String(), "--store", dir)
+	Expect(code).ShouldNot(Equal(0))
+	Expect(stderr).Should(ContainSubstring("before today"))
+}
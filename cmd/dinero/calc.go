@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// runCalc evaluates a mixed-currency expression, printing the result and the
+// rates used to reach it.
+func runCalc(env *environment, args []string) error {
+	fs := env.flags()
+	env.snapshotFlags(fs, true)
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return errors.New("expected EXPRESSION")
+	}
+
+	expr, err := parseExpression(strings.Join(positional, " "))
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client("")
+	if err != nil {
+		return err
+	}
+
+	// Resolve every conversion through the rates services, noting the
+	// rates used along the way.
+	used := &table{name: "rates", header: []string{"From", "To", "Rate"}}
+	seen := map[string]bool{}
+	convert := func(amount float64, from, to string) (float64, error) {
+		convert := client.Rates.Convert
+		if !expr.date.IsZero() {
+			convert = func(amount float64, from, to string) (float64, error) {
+				return client.HistoricalRates.ConvertOn(amount, from, to, expr.date)
+			}
+		}
+
+		if pair := from + "/" + to; !seen[pair] {
+			rate, err := convert(1, from, to)
+			if err != nil {
+				return 0, err
+			}
+			seen[pair] = true
+			used.add(from, to, rate)
+		}
+		return convert(amount, from, to)
+	}
+
+	result, err := expr.eval(convert)
+	if err != nil {
+		return err
+	}
+
+	date := "latest"
+	if !expr.date.IsZero() {
+		date = expr.date.String()
+	}
+	summary := &table{name: "result", header: []string{"Result", "Code", "Date"}}
+	summary.add(result.amount, result.code, date)
+
+	return writeTables(env.stdout, env.format, summary, used)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestCLI_Calc will test evaluating an expression and listing the rates used.
+func TestCLI_Calc(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	getenv := newTestEnv(t)
+
+	code, stdout, _ := runTest(getenv, "calc", "100 USD + 30 NZD in AUD", "--format", "csv")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(HavePrefix("Result,Code,Date\n150,AUD,latest\n\nFrom,To,Rate\nUSD,AUD,1.25\nNZD,AUD,0.83333"))
+
+	code, stdout, _ = runTest(getenv, "calc", "130 AUD @ 2021-01-02 in USD", "--format", "json")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(MatchJSON(`{
+		"result": [{"result": 100, "code": "USD", "date": "2021-01-02"}],
+		"rates": [{"from": "AUD", "to": "USD", "rate": 0.7692307692307692}]
+	}`))
+
+	code, _, stderr := runTest(getenv, "calc", "100 USD +")
+	Expect(code).Should(Equal(1))
+	Expect(stderr).Should(ContainSubstring("unexpected end of expression"))
+}
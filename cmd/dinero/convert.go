@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattevans/dinero"
+)
+
+// runConvert converts an amount between two currencies, at the latest rates
+// or those for a given date.
+func runConvert(env *environment, args []string) error {
+	fs := env.flags()
+	env.snapshotFlags(fs, true)
+	date := fs.String("date", "", "convert at the rates for this date (YYYY-MM-DD)")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		return errors.New("expected AMOUNT FROM TO")
+	}
+
+	amount, err := strconv.ParseFloat(positional[0], 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", positional[0])
+	}
+	from, to := strings.ToUpper(positional[1]), strings.ToUpper(positional[2])
+
+	client, err := env.client("")
+	if err != nil {
+		return err
+	}
+
+	convert := client.Rates.Convert
+	if *date != "" {
+		on, err := dinero.ParseDate(*date)
+		if err != nil {
+			return err
+		}
+		convert = func(amount float64, from, to string) (float64, error) {
+			return client.HistoricalRates.ConvertOn(amount, from, to, on)
+		}
+	}
+
+	converted, err := convert(amount, from, to)
+	if err != nil {
+		return err
+	}
+	// The rate can't be had from a zero amount, so convert a single unit.
+	rate := converted / amount
+	if amount == 0 {
+		if rate, err = convert(1, from, to); err != nil {
+			return err
+		}
+	}
+
+	t := &table{header: []string{"Amount", "From", "Result", "To", "Rate"}}
+	t.add(amount, from, converted, to, rate)
+	return t.write(env.stdout, env.format)
+}
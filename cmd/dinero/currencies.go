@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// runCurrencies lists the available currencies, optionally filtered by a
+// search term matched against the code and name.
+func runCurrencies(env *environment, args []string) error {
+	fs := env.flags()
+	search := fs.String("search", "", "only list currencies whose code or name contains this term")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("unexpected arguments")
+	}
+
+	client, err := env.client("")
+	if err != nil {
+		return err
+	}
+
+	currencies, err := client.Currencies.List()
+	if err != nil {
+		return err
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+
+	term := strings.ToLower(*search)
+	t := &table{header: []string{"Code", "Name"}}
+	for _, currency := range currencies {
+		if term == "" ||
+			strings.Contains(strings.ToLower(currency.Code), term) ||
+			strings.Contains(strings.ToLower(currency.Name), term) {
+			t.add(currency.Code, currency.Name)
+		}
+	}
+	return t.write(env.stdout, env.format)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattevans/dinero"
+)
+
+const (
+	appIDEnv      = "OPEN_EXCHANGE_APP_ID"
+	configEnv     = "DINERO_CONFIG"
+	backendURLEnv = "DINERO_BACKEND_URL"
+	dateLayout    = "2006-01-02"
+)
+
+// config holds the settings read from the config file.
+type config struct {
+	AppID      string `json:"app_id"`
+	Base       string `json:"base"`
+	BackendURL string `json:"backend_url"`
+	Snapshot   string `json:"snapshot"`
+}
+
+// environment holds everything a command needs to run.
+type environment struct {
+	name   string
+	getenv func(string) string
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	format     string
+	snapshot   string
+	offline    bool
+}
+
+// flags returns a flag set for the command with the common flags registered.
+func (e *environment) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(e.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(&e.configPath, "config", "", "path to the config file")
+	fs.StringVar(&e.format, "format", formatTable, "output format: table, json or csv")
+	return fs
+}
+
+// snapshotFlags registers the flags for commands that can use the local
+// snapshot file.
+func (e *environment) snapshotFlags(fs *flag.FlagSet, offline bool) {
+	fs.StringVar(&e.snapshot, "snapshot", "", "path to the local snapshot file")
+	if offline {
+		fs.BoolVar(&e.offline, "offline", false, "read rates only from the local snapshot file")
+	}
+}
+
+// parse parses the flags in args, allowing them to be interleaved with
+// positional arguments, and returns the positional arguments.
+func (e *environment) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	switch e.format {
+	case formatTable, formatJSON, formatCSV:
+		return positional, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", e.format)
+}
+
+// config loads the config file, if there is one, with the environment
+// variables taking precedence over it.
+func (e *environment) config() (*config, error) {
+	cfg := &config{}
+
+	path, explicit := e.configPath, true
+	if path == "" {
+		path = e.getenv(configEnv)
+	}
+	if path == "" {
+		explicit = false
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "dinero", "config.json")
+		}
+	}
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, fmt.Errorf("reading config %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	if appID := e.getenv(appIDEnv); appID != "" {
+		cfg.AppID = appID
+	}
+	if backendURL := e.getenv(backendURLEnv); backendURL != "" {
+		cfg.BackendURL = backendURL
+	}
+	return cfg, nil
+}
+
+// client builds a dinero client from the config, using base as the base
+// currency if it's set.
+func (e *environment) client(base string) (*dinero.Client, error) {
+	cfg, err := e.config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AppID == "" && !e.offline {
+		return nil, fmt.Errorf("no app ID: set %s or app_id in the config file", appIDEnv)
+	}
+	if base == "" {
+		base = cfg.Base
+	}
+
+	client := dinero.NewClient(cfg.AppID, base, 1*time.Hour)
+	if e.offline {
+		store, err := e.store(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.warnStale(store); err != nil {
+			return nil, err
+		}
+		client.Store = store
+		client.Offline = true
+	}
+	if cfg.BackendURL != "" {
+		backendURL, err := url.Parse(cfg.BackendURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend URL: %w", err)
+		}
+		client.BackendURL = backendURL
+	}
+	return client, nil
+}
+
+// store opens the local snapshot file, from the --snapshot flag, the config
+// file, or the user's cache directory, in that order.
+func (e *environment) store(cfg *config) (*dinero.FileStore, error) {
+	path := e.snapshot
+	if path == "" {
+		path = cfg.Snapshot
+	}
+	if path == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("no snapshot path: %w", err)
+		}
+		path = filepath.Join(dir, "dinero", "snapshot.json")
+	}
+	return dinero.NewFileStore(path)
+}
+
+// warnStale reports how old the snapshot is, flagging it as a warning once
+// it's more than a day old.
+func (e *environment) warnStale(store *dinero.FileStore) error {
+	updated := store.UpdatedAt()
+	if updated.IsZero() {
+		return errors.New("no offline snapshot, run 'dinero sync' first")
+	}
+
+	age := time.Since(updated).Truncate(time.Minute)
+	prefix := "note"
+	if age > 24*time.Hour {
+		prefix = "warning"
+	}
+	fmt.Fprintf(e.stderr, "%s: using offline rates synced %s (%s ago)\n", prefix, updated.Format(time.RFC3339), age)
+	return nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattevans/dinero"
+)
+
+// expression is a parsed calc expression, e.g.
+// "120 USD + 45.50 EUR - 3000 JPY in AUD @ 2021-06-30".
+type expression struct {
+	root node
+	// target is the currency the result is given in. It defaults to the
+	// first currency in the expression.
+	target string
+	// date, if set, is the date whose rates are used.
+	date dinero.Date
+}
+
+// node is a node in the expression tree.
+type node interface{}
+
+// literal is an amount, with a currency code unless it's a scalar.
+type literal struct {
+	amount float64
+	code   string
+}
+
+// binary is an arithmetic operation on two nodes.
+type binary struct {
+	op          string
+	left, right node
+}
+
+// negate is a unary minus.
+type negate struct {
+	operand node
+}
+
+// value is the result of evaluating a node.
+type value struct {
+	amount float64
+	// code is empty for scalars.
+	code string
+}
+
+// token is a lexical token in an expression.
+type token struct {
+	kind string
+	text string
+}
+
+const (
+	tokenNumber = "number"
+	tokenDate   = "date"
+	tokenCode   = "code"
+	tokenIn     = "in"
+	tokenOp     = "op"
+	tokenEOF    = "eof"
+)
+
+var (
+	datePattern   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	numberPattern = regexp.MustCompile(`^(\d+(\.\d*)?|\.\d+)`)
+	codePattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*`)
+)
+
+// lex splits an expression into tokens.
+func lex(input string) ([]token, error) {
+	tokens := []token{}
+	for rest := strings.TrimSpace(input); rest != ""; rest = strings.TrimSpace(rest) {
+		var tok token
+		switch {
+		case datePattern.MatchString(rest):
+			tok = token{tokenDate, datePattern.FindString(rest)}
+		case numberPattern.MatchString(rest):
+			tok = token{tokenNumber, numberPattern.FindString(rest)}
+		case codePattern.MatchString(rest):
+			text := codePattern.FindString(rest)
+			if strings.EqualFold(text, "in") {
+				tok = token{tokenIn, text}
+			} else {
+				tok = token{tokenCode, text}
+			}
+		case strings.ContainsAny(rest[:1], "+-*/()@"):
+			tok = token{tokenOp, rest[:1]}
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest[:1])
+		}
+		tokens = append(tokens, tok)
+		rest = rest[len(tok.text):]
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// parser is a recursive descent parser over the grammar:
+//
+//	calc    = expr { "in" CODE | "@" DATE }
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = NUMBER [ CODE ] | "(" expr ")"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression parses a calc expression.
+func parseExpression(input string) (*expression, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	expr := &expression{root: root}
+
+	for p.peek().kind != tokenEOF {
+		switch tok := p.next(); {
+		case tok.kind == tokenIn && expr.target == "":
+			code := p.next()
+			if code.kind != tokenCode {
+				return nil, errors.New("expected a currency code after 'in'")
+			}
+			expr.target = strings.ToUpper(code.text)
+		case tok.kind == tokenOp && tok.text == "@" && expr.date.IsZero():
+			date := p.next()
+			if date.kind != tokenDate {
+				return nil, errors.New("expected a YYYY-MM-DD date after '@'")
+			}
+			if expr.date, err = dinero.ParseDate(date.text); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", tok.text)
+		}
+	}
+
+	if expr.target == "" {
+		expr.target = firstCode(root)
+	}
+	if expr.target == "" {
+		return nil, errors.New("expression has no currency")
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &negate{operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	if _, ok := p.accept("("); ok {
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, errors.New("expected ')'")
+		}
+		return inner, nil
+	}
+
+	tok := p.next()
+	if tok.kind != tokenNumber {
+		if tok.kind == tokenEOF {
+			return nil, errors.New("unexpected end of expression")
+		}
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+	amount, err := strconv.ParseFloat(tok.text, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	lit := &literal{amount: amount}
+	if p.peek().kind == tokenCode {
+		lit.code = strings.ToUpper(p.next().text)
+	}
+	return lit, nil
+}
+
+// firstCode returns the first currency code in the tree, in reading order.
+func firstCode(n node) string {
+	switch n := n.(type) {
+	case *literal:
+		return n.code
+	case *negate:
+		return firstCode(n.operand)
+	case *binary:
+		if code := firstCode(n.left); code != "" {
+			return code
+		}
+		return firstCode(n.right)
+	}
+	return ""
+}
+
+// eval evaluates the expression, converting every amount to the target
+// currency with convert.
+func (e *expression) eval(convert func(amount float64, from, to string) (float64, error)) (value, error) {
+	return e.evalNode(e.root, convert)
+}
+
+func (e *expression) evalNode(n node, convert func(amount float64, from, to string) (float64, error)) (value, error) {
+	switch n := n.(type) {
+	case *literal:
+		if n.code == "" {
+			return value{amount: n.amount}, nil
+		}
+		amount, err := convert(n.amount, n.code, e.target)
+		if err != nil {
+			return value{}, err
+		}
+		return value{amount: amount, code: e.target}, nil
+
+	case *negate:
+		operand, err := e.evalNode(n.operand, convert)
+		operand.amount = -operand.amount
+		return operand, err
+
+	case *binary:
+		left, err := e.evalNode(n.left, convert)
+		if err != nil {
+			return value{}, err
+		}
+		right, err := e.evalNode(n.right, convert)
+		if err != nil {
+			return value{}, err
+		}
+		return apply(n.op, left, right)
+	}
+	return value{}, fmt.Errorf("unknown node %T", n)
+}
+
+// apply performs a single arithmetic operation. Amounts can be added to and
+// subtracted from each other, and multiplied or divided by scalars; dividing
+// one amount by another gives a scalar.
+func apply(op string, left, right value) (value, error) {
+	money := left.code != "" || right.code != ""
+	switch op {
+	case "+", "-":
+		if (left.code == "") != (right.code == "") {
+			return value{}, fmt.Errorf("can't %s an amount and a scalar", op)
+		}
+		if op == "-" {
+			right.amount = -right.amount
+		}
+		return value{amount: left.amount + right.amount, code: left.code}, nil
+
+	case "*":
+		if left.code != "" && right.code != "" {
+			return value{}, errors.New("can't multiply two amounts")
+		}
+		code := left.code
+		if code == "" {
+			code = right.code
+		}
+		return value{amount: left.amount * right.amount, code: code}, nil
+
+	case "/":
+		if left.code == "" && money {
+			return value{}, errors.New("can't divide a scalar by an amount")
+		}
+		if right.amount == 0 {
+			return value{}, errors.New("division by zero")
+		}
+		code := left.code
+		if right.code != "" {
+			code = ""
+		}
+		return value{amount: left.amount / right.amount, code: code}, nil
+	}
+	return value{}, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattevans/dinero"
+	. "github.com/onsi/gomega"
+)
+
+// TestExpression_Eval will test parsing and evaluating mixed-currency expressions.
+func TestExpression_Eval(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// One unit of each currency is worth this many USD.
+	usd := map[string]float64{"USD": 1, "EUR": 1.2, "JPY": 0.01, "AUD": 0.8}
+	convert := func(amount float64, from, to string) (float64, error) {
+		return amount * usd[from] / usd[to], nil
+	}
+
+	var tests = []struct {
+		input    string
+		expected value
+	}{
+		{"120 USD + 45.50 EUR - 3000 JPY in AUD", value{(120 + 45.5*1.2 - 30) / 0.8, "AUD"}},
+		{"(100 usd + 100 EUR) * 2", value{440, "USD"}},
+		{"-10 EUR / 4 in USD", value{-3, "USD"}},
+		{"100 EUR / 50 USD", value{2.4, ""}},
+		{"2 * 3 USD - 1 USD", value{5, "USD"}},
+	}
+
+	for _, test := range tests {
+		expr, err := parseExpression(test.input)
+		Expect(err).Should(BeNil(), test.input)
+
+		actual, err := expr.eval(convert)
+		Expect(err).Should(BeNil(), test.input)
+		Expect(actual.code).Should(Equal(test.expected.code), test.input)
+		Expect(actual.amount).Should(BeNumerically("~", test.expected.amount, 1e-9), test.input)
+	}
+}
+
+// TestExpression_Parse will test expression suffixes and invalid expressions.
+func TestExpression_Parse(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	expr, err := parseExpression("100 USD @ 2021-06-30 in EUR")
+	Expect(err).Should(BeNil())
+	Expect(expr.target).Should(Equal("EUR"))
+	Expect(expr.date).Should(Equal(dinero.NewDate(2021, 6, 30)))
+
+	for _, input := range []string{
+		"",
+		"100 + 5",
+		"100 USD +",
+		"(100 USD",
+		"100 USD in",
+		"100 USD @ tomorrow",
+		"100 USD in EUR in AUD",
+		"100 USD $ 5",
+	} {
+		_, err := parseExpression(input)
+		Expect(err).ShouldNot(BeNil(), input)
+	}
+
+	convert := func(amount float64, from, to string) (float64, error) {
+		return amount, nil
+	}
+	for _, input := range []string{
+		"100 USD + 5",
+		"100 USD * 5 EUR",
+		"5 / 100 USD",
+		"100 USD / 0",
+	} {
+		expr, err := parseExpression(input)
+		Expect(err).Should(BeNil(), input)
+		_, err = expr.eval(convert)
+		Expect(err).ShouldNot(BeNil(), input)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mattevans/dinero"
+)
+
+// runHistory lists a currency's daily rates over a date range.
+func runHistory(env *environment, args []string) error {
+	fs := env.flags()
+	env.snapshotFlags(fs, true)
+	base := fs.String("base", "", "base currency (defaults to the configured base, or USD)")
+	fromFlag := fs.String("from", "", "first date of the range (YYYY-MM-DD)")
+	toFlag := fs.String("to", "", "last date of the range (YYYY-MM-DD)")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("expected CODE")
+	}
+	code := strings.ToUpper(positional[0])
+
+	from, err := dinero.ParseDate(*fromFlag)
+	if err != nil {
+		return err
+	}
+	to, err := dinero.ParseDate(*toFlag)
+	if err != nil {
+		return err
+	}
+	if to.Before(from) {
+		return errors.New("--to must not be before --from")
+	}
+
+	client, err := env.client(*base)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"Date", "Base", "Code", "Rate"}}
+	for date := from; !date.After(to); date = date.AddDays(1) {
+		rate, err := client.HistoricalRates.GetOn(code, date)
+		if err != nil {
+			return err
+		}
+		t.add(date.String(), client.HistoricalRates.GetBaseCurrency(), code, *rate)
+	}
+	return t.write(env.stdout, env.format)
+}
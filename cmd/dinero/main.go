@@ -0,0 +1,91 @@
+// Command dinero is a command-line client for the Open Exchange Rates API,
+// built on the dinero library.
+//
+// Usage:
+//
+//	dinero rates [--base CODE] [--symbols CODE,CODE]
+//	dinero convert AMOUNT FROM TO [--date YYYY-MM-DD]
+//	dinero calc "120 USD + 45.50 EUR - 3000 JPY in AUD @ 2021-06-30"
+//	dinero currencies [--search TERM]
+//	dinero history CODE --from YYYY-MM-DD --to YYYY-MM-DD [--base CODE]
+//	dinero sync [--base CODE] [--days N]
+//	dinero backfill --from YYYY-MM-DD [--to YYYY-MM-DD] --store DIR [--base CODE]
+//	dinero audit --from YYYY-MM-DD [--to YYYY-MM-DD] [--store DIR] [--repair]
+//
+// Every command accepts --format (table, json or csv) and --config. The
+// rates, convert, calc and history commands also accept --offline, which
+// reads rates only from the local snapshot file written by sync. The OXR
+// app ID is read from the OPEN_EXCHANGE_APP_ID environment variable, or from
+// the app_id field of the config file (by default dinero/config.json in the
+// user's config directory, or the path in DINERO_CONFIG).
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: dinero <command> [flags]
+
+Commands:
+  rates       List the latest rates
+  convert     Convert an amount between currencies
+  calc        Evaluate a mixed-currency expression
+  currencies  List available currencies
+  history     List a currency's rates over a date range
+  sync        Save the latest and recent rates to the local snapshot file
+  backfill    Fetch missing historical rates into a rate store
+  audit       Find and repair gaps and bad values in a rate store
+
+Run 'dinero <command> -h' for a command's flags.
+`
+
+// command runs a single subcommand against the given arguments.
+type command func(env *environment, args []string) error
+
+var commands = map[string]command{
+	"rates":      runRates,
+	"convert":    runConvert,
+	"calc":       runCalc,
+	"currencies": runCurrencies,
+	"history":    runHistory,
+	"sync":       runSync,
+	"backfill":   runBackfill,
+	"audit":      runAudit,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+// run executes the command line given in args, returning the exit code.
+func run(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "dinero: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	env := &environment{
+		name:   args[0],
+		getenv: getenv,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	if err := cmd(env, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "dinero %s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
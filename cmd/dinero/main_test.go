@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// newTestEnv starts a fake OXR API and returns a getenv func pointing the CLI at it.
+func newTestEnv(t *testing.T) func(string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/usage.json" {
+			_, _ = w.Write([]byte(`{"status": 200, "data": {"usage": {"requests": 0, "requests_quota": 1000, "requests_remaining": 1000}}}`))
+			return
+		}
+		if r.URL.Path == "/api/currencies.json" {
+			_, _ = w.Write([]byte(`{"AUD": "Australian Dollar", "NZD": "New Zealand Dollar", "USD": "United States Dollar"}`))
+			return
+		}
+
+		rates := map[string]float64{"USD": 1, "AUD": 1.25, "NZD": 1.5}
+		if strings.HasPrefix(r.URL.Path, "/api/historical/2021-01-02") {
+			rates["AUD"] = 1.3
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"base":      "USD",
+			"timestamp": 1609459200,
+			"rates":     rates,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	env := map[string]string{
+		appIDEnv:      "12345",
+		backendURLEnv: server.URL,
+		configEnv:     "",
+	}
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+// runTest runs the CLI with args, returning the exit code and output.
+func runTest(getenv func(string) string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, getenv, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestCLI_Rates will test listing rates in each output format.
+func TestCLI_Rates(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	getenv := newTestEnv(t)
+
+	code, stdout, _ := runTest(getenv, "rates", "--symbols", "nzd,AUD")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(Equal("Base  Code  Rate\nUSD   NZD   1.5\nUSD   AUD   1.25\n"))
+
+	code, stdout, _ = runTest(getenv, "rates", "--format", "csv")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(Equal("Base,Code,Rate\nUSD,AUD,1.25\nUSD,NZD,1.5\nUSD,USD,1\n"))
+
+	code, stdout, _ = runTest(getenv, "rates", "--format", "json", "--symbols", "AUD")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(MatchJSON(`[{"base": "USD", "code": "AUD", "rate": 1.25}]`))
+}
+
+// TestCLI_Convert will test converting amounts at the latest and historical rates.
+func TestCLI_Convert(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	getenv := newTestEnv(t)
+
+	code, stdout, _ := runTest(getenv, "convert", "100", "aud", "nzd", "--format", "csv")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(Equal("Amount,From,Result,To,Rate\n100,AUD,120,NZD,1.2\n"))
+
+	code, stdout, _ = runTest(getenv, "convert", "130", "AUD", "USD", "--date", "2021-01-02", "--format", "csv")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(HavePrefix("Amount,From,Result,To,Rate\n130,AUD,100,USD,"))
+
+	code, stdout, _ = runTest(getenv, "convert", "0", "AUD", "NZD", "--format", "json")
+	Expect(code).Should(Equal(0))
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(stdout), &rows)).Should(BeNil())
+	Expect(rows).Should(HaveLen(1))
+	Expect(rows[0]["result"]).Should(BeNumerically("==", 0))
+	Expect(rows[0]["rate"]).Should(BeNumerically("~", 1.2, 1e-9))
+
+	code, _, stderr := runTest(getenv, "convert", "100", "AUD")
+	Expect(code).Should(Equal(1))
+	Expect(stderr).Should(ContainSubstring("expected AMOUNT FROM TO"))
+}
+
+// TestCLI_Currencies will test listing and searching currencies.
+func TestCLI_Currencies(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	getenv := newTestEnv(t)
+
+	code, stdout, _ := runTest(getenv, "currencies", "--search", "zealand", "--format", "csv")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(Equal("Code,Name\nNZD,New Zealand Dollar\n"))
+}
+
+// TestCLI_History will test listing a currency's rates over a date range.
+func TestCLI_History(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	getenv := newTestEnv(t)
+
+	code, stdout, _ := runTest(getenv, "history", "AUD", "--from", "2021-01-01", "--to", "2021-01-03", "--format", "csv")
+	Expect(code).Should(Equal(0))
+	Expect(stdout).Should(Equal("Date,Base,Code,Rate\n2021-01-01,USD,AUD,1.25\n2021-01-02,USD,AUD,1.3\n2021-01-03,USD,AUD,1.25\n"))
+}
+
+// TestCLI_Config will test a missing config file or app ID and unknown commands are rejected.
+func TestCLI_Config(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	code, _, stderr := runTest(func(string) string { return "" }, "rates", "--config", "testdata/missing.json")
+	Expect(code).Should(Equal(1))
+	Expect(stderr).Should(ContainSubstring("missing.json"))
+
+	config := filepath.Join(t.TempDir(), "config.json")
+	Expect(ioutil.WriteFile(config, []byte(`{"base": "AUD"}`), 0644)).Should(BeNil())
+	code, _, stderr = runTest(func(string) string { return "" }, "rates", "--config", config)
+	Expect(code).Should(Equal(1))
+	Expect(stderr).Should(ContainSubstring("no app ID"))
+
+	code, _, stderr = runTest(func(string) string { return "" }, "bogus")
+	Expect(code).Should(Equal(2))
+	Expect(stderr).Should(ContainSubstring("unknown command"))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// table is the tabular result of a command.
+type table struct {
+	// name identifies the table when several are written together.
+	name   string
+	header []string
+	rows   [][]interface{}
+}
+
+// add appends a row to the table.
+func (t *table) add(values ...interface{}) {
+	t.rows = append(t.rows, values)
+}
+
+// write renders the table to w in the given format. JSON output is an array
+// of objects keyed by the lower-cased header.
+func (t *table) write(w io.Writer, format string) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, t.objects())
+
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(t.header); err != nil {
+			return err
+		}
+		for _, row := range t.rows {
+			if err := cw.Write(t.strings(row)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(t.strings(row), "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// writeTables renders several tables to w in the given format. Table and CSV
+// output separates them with a blank line, and JSON output is an object keyed
+// by each table's name.
+func writeTables(w io.Writer, format string, tables ...*table) error {
+	if format == formatJSON {
+		objects := map[string]interface{}{}
+		for _, t := range tables {
+			objects[t.name] = t.objects()
+		}
+		return writeJSON(w, objects)
+	}
+
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := t.write(w, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objects returns the rows as objects keyed by the lower-cased header.
+func (t *table) objects() []map[string]interface{} {
+	objects := make([]map[string]interface{}, 0, len(t.rows))
+	for _, row := range t.rows {
+		object := map[string]interface{}{}
+		for i, value := range row {
+			object[strings.ToLower(t.header[i])] = value
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (t *table) strings(row []interface{}) []string {
+	values := make([]string, len(row))
+	for i, value := range row {
+		values[i] = formatValue(value)
+	}
+	return values
+}
+
+func formatValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// runRates lists the latest rates, optionally limited to a set of symbols.
+func runRates(env *environment, args []string) error {
+	fs := env.flags()
+	env.snapshotFlags(fs, true)
+	base := fs.String("base", "", "base currency (defaults to the configured base, or USD)")
+	symbols := fs.String("symbols", "", "comma-separated currency codes to list")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("unexpected arguments")
+	}
+
+	client, err := env.client(*base)
+	if err != nil {
+		return err
+	}
+
+	rsp, err := client.Rates.List()
+	if err != nil {
+		return err
+	}
+
+	return ratesTable(rsp.Base, rsp.Rates, *symbols).write(env.stdout, env.format)
+}
+
+// ratesTable builds a table of the given rates, sorted by code and limited to
+// the comma-separated symbols if any are given.
+func ratesTable(base string, rates map[string]float64, symbols string) *table {
+	codes := []string{}
+	if symbols != "" {
+		for _, code := range strings.Split(symbols, ",") {
+			if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+				codes = append(codes, code)
+			}
+		}
+	} else {
+		for code := range rates {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+	}
+
+	t := &table{header: []string{"Base", "Code", "Rate"}}
+	for _, code := range codes {
+		if rate, ok := rates[code]; ok {
+			t.add(base, code, rate)
+		}
+	}
+	return t
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+)
+
+// runSync saves the latest rates, and those for a window of previous days,
+// to the local snapshot file for use with --offline.
+func runSync(env *environment, args []string) error {
+	fs := env.flags()
+	env.snapshotFlags(fs, false)
+	base := fs.String("base", "", "base currency (defaults to the configured base, or USD)")
+	days := fs.Int("days", 30, "number of previous days of historical rates to save")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("unexpected arguments")
+	}
+	if *days < 0 {
+		return errors.New("--days must not be negative")
+	}
+
+	cfg, err := env.config()
+	if err != nil {
+		return err
+	}
+	store, err := env.store(cfg)
+	if err != nil {
+		return err
+	}
+	client, err := env.client(*base)
+	if err != nil {
+		return err
+	}
+	client.Store = store
+
+	t := &table{header: []string{"Date", "Base", "Rates"}}
+
+	// Write the snapshot once, rather than for every day saved.
+	err = store.Batch(func() error {
+		latest, err := client.Rates.List()
+		if err != nil {
+			return err
+		}
+		end := today()
+		t.add(end.String(), latest.Base, len(latest.Rates))
+
+		// Historical rates are saved under the base OXR actually used.
+		client.HistoricalRates.SetBaseCurrency(latest.Base)
+		for i := 1; i <= *days; i++ {
+			date := end.AddDays(-i)
+			rsp, err := client.HistoricalRates.ListOn(date)
+			if err != nil {
+				return err
+			}
+			t.add(date.String(), rsp.Base, len(rsp.Rates))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.write(env.stdout, env.format)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestCLI_Sync will test syncing a snapshot and reading from it offline.
+func TestCLI_Sync(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+	getenv := newTestEnv(t)
+
+	code, stdout, stderr := runTest(getenv, "sync", "--snapshot", snapshot, "--days", "2", "--format", "csv")
+	Expect(code).Should(Equal(0), stderr)
+	Expect(strings.Count(stdout, "\n")).Should(Equal(4))
+
+	// Offline, no app ID or network is needed.
+	offline := func(string) string { return "" }
+
+	code, stdout, stderr = runTest(offline, "rates", "--offline", "--snapshot", snapshot, "--symbols", "AUD", "--format", "csv")
+	Expect(code).Should(Equal(0), stderr)
+	Expect(stdout).Should(Equal("Base,Code,Rate\nUSD,AUD,1.25\n"))
+	Expect(stderr).Should(HavePrefix("note: using offline rates synced"))
+
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(dateLayout)
+	code, stdout, stderr = runTest(offline, "history", "NZD", "--offline", "--snapshot", snapshot, "--from", yesterday, "--to", yesterday, "--format", "csv")
+	Expect(code).Should(Equal(0), stderr)
+	Expect(stdout).Should(Equal("Date,Base,Code,Rate\n" + yesterday + ",USD,NZD,1.5\n"))
+
+	code, _, stderr = runTest(offline, "history", "NZD", "--offline", "--snapshot", snapshot, "--from", "2000-01-01", "--to", "2000-01-01")
+	Expect(code).Should(Equal(1))
+	Expect(stderr).Should(ContainSubstring("rates not available offline"))
+
+	// Without a snapshot there's nothing to read.
+	code, _, stderr = runTest(offline, "rates", "--offline", "--snapshot", filepath.Join(t.TempDir(), "missing.json"))
+	Expect(code).Should(Equal(1))
+	Expect(stderr).Should(ContainSubstring("run 'dinero sync' first"))
+}
@@ -1,12 +1,15 @@
 package dinero
 
+import "context"
+
 const (
 	currenciesAPIPath = "currencies.json"
 )
 
 // CurrenciesService handles currency request/responses.
 type CurrenciesService struct {
-	client *Client
+	client        *Client
+	includeCustom bool
 }
 
 // NewCurrenciesService creates a new handler for this service.
@@ -14,7 +17,7 @@ func NewCurrenciesService(
 	client *Client,
 ) *CurrenciesService {
 	return &CurrenciesService{
-		client,
+		client: client,
 	}
 }
 
@@ -26,6 +29,14 @@ type CurrencyResponse struct {
 
 // List will fetch all list of all currencies available via the OXR api.
 func (s *CurrenciesService) List() ([]*CurrencyResponse, error) {
+	return s.ListContext(context.Background())
+}
+
+// ListContext is like List, tracing the call within ctx.
+func (s *CurrenciesService) ListContext(ctx context.Context) (currencies []*CurrencyResponse, err error) {
+	ctx, span := s.client.startSpan(ctx, "dinero.Currencies.List")
+	defer func() { endSpan(span, err) }()
+
 	// Build request.
 	req, err := s.client.NewUnauthedRequest(
 		"GET",
@@ -35,6 +46,7 @@ func (s *CurrenciesService) List() ([]*CurrencyResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	// Make request.
 	rsp := map[string]string{}
@@ -50,5 +62,19 @@ func (s *CurrenciesService) List() ([]*CurrencyResponse, error) {
 			Name: name,
 		})
 	}
+
+	if s.includeCustom {
+		for _, currency := range s.client.CustomCurrencies.List() {
+			latest = append(latest, &CurrencyResponse{
+				Code: currency.Code,
+				Name: currency.Name,
+			})
+		}
+	}
 	return latest, nil
 }
+
+// SetIncludeCustom will set whether List includes registered custom currencies.
+func (s *CurrenciesService) SetIncludeCustom(include bool) {
+	s.includeCustom = include
+}
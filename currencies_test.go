@@ -1,9 +1,7 @@
 package dinero
 
 import (
-	"os"
 	"testing"
-	"time"
 
 	. "github.com/onsi/gomega"
 )
@@ -14,7 +12,7 @@ func TestCurrencies_List(t *testing.T) {
 	RegisterTestingT(t)
 
 	// Init dinero client.
-	client := NewClient(os.Getenv("OPEN_EXCHANGE_APP_ID"), "AUD", 1*time.Minute)
+	client := newClient(t, "AUD")
 
 	// List the currencies
 	rsp, err := client.Currencies.List()
@@ -0,0 +1,221 @@
+package dinero
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrCustomBase is returned when a custom currency is used as the base
+	// for fetching rates, as OXR knows nothing of it.
+	ErrCustomBase = errors.New("custom currency can't be used as a base")
+
+	// ErrRealCurrency is returned when registering a custom currency under a
+	// code OXR already serves, which would shadow its rates.
+	ErrRealCurrency = errors.New("custom currency code is a real currency")
+)
+
+// servedCurrencies holds the codes OXR serves rates for, including the
+// alternative currencies and precious metals.
+var servedCurrencies = map[string]bool{
+	"AED": true, "AFN": true, "ALL": true, "AMD": true, "ANG": true, "AOA": true,
+	"ARS": true, "AUD": true, "AWG": true, "AZN": true, "BAM": true, "BBD": true,
+	"BDT": true, "BGN": true, "BHD": true, "BIF": true, "BMD": true, "BND": true,
+	"BOB": true, "BRL": true, "BSD": true, "BTC": true, "BTN": true, "BWP": true,
+	"BYN": true, "BZD": true, "CAD": true, "CDF": true, "CHF": true, "CLF": true,
+	"CLP": true, "CNH": true, "CNY": true, "COP": true, "CRC": true, "CUC": true,
+	"CUP": true, "CVE": true, "CZK": true, "DJF": true, "DKK": true, "DOP": true,
+	"DZD": true, "EGP": true, "ERN": true, "ETB": true, "EUR": true, "FJD": true,
+	"FKP": true, "GBP": true, "GEL": true, "GGP": true, "GHS": true, "GIP": true,
+	"GMD": true, "GNF": true, "GTQ": true, "GYD": true, "HKD": true, "HNL": true,
+	"HRK": true, "HTG": true, "HUF": true, "IDR": true, "ILS": true, "IMP": true,
+	"INR": true, "IQD": true, "IRR": true, "ISK": true, "JEP": true, "JMD": true,
+	"JOD": true, "JPY": true, "KES": true, "KGS": true, "KHR": true, "KMF": true,
+	"KPW": true, "KRW": true, "KWD": true, "KYD": true, "KZT": true, "LAK": true,
+	"LBP": true, "LKR": true, "LRD": true, "LSL": true, "LYD": true, "MAD": true,
+	"MDL": true, "MGA": true, "MKD": true, "MMK": true, "MNT": true, "MOP": true,
+	"MRU": true, "MUR": true, "MVR": true, "MWK": true, "MXN": true, "MYR": true,
+	"MZN": true, "NAD": true, "NGN": true, "NIO": true, "NOK": true, "NPR": true,
+	"NZD": true, "OMR": true, "PAB": true, "PEN": true, "PGK": true, "PHP": true,
+	"PKR": true, "PLN": true, "PYG": true, "QAR": true, "RON": true, "RSD": true,
+	"RUB": true, "RWF": true, "SAR": true, "SBD": true, "SCR": true, "SDG": true,
+	"SEK": true, "SGD": true, "SHP": true, "SLE": true, "SLL": true, "SOS": true,
+	"SRD": true, "SSP": true, "STD": true, "STN": true, "SVC": true, "SYP": true,
+	"SZL": true, "THB": true, "TJS": true, "TMT": true, "TND": true, "TOP": true,
+	"TRY": true, "TTD": true, "TWD": true, "TZS": true, "UAH": true, "UGX": true,
+	"USD": true, "UYU": true, "UZS": true, "VES": true, "VND": true, "VUV": true,
+	"WST": true, "XAF": true, "XAG": true, "XAU": true, "XCD": true, "XDR": true,
+	"XOF": true, "XPD": true, "XPF": true, "XPT": true, "YER": true, "ZAR": true,
+	"ZMW": true, "ZWL": true,
+}
+
+// isRealCurrency reports whether code is served by OXR or known to us
+// through a fixed rate or redenomination.
+func isRealCurrency(code string) bool {
+	if servedCurrencies[code] {
+		return true
+	}
+	if _, ok := fixedRates[code]; ok {
+		return true
+	}
+	_, from := successor(code)
+	_, to := predecessor(code)
+	return from || to
+}
+
+// CustomCurrency is a user-defined currency or virtual unit (e.g. loyalty
+// points) with a defined relationship to a real currency.
+type CustomCurrency struct {
+	Code string
+	Name string
+	// Anchor is the real currency the custom currency is defined against.
+	Anchor string
+	// Rate is the number of units of the custom currency per one unit of
+	// Anchor.
+	Rate float64
+	// RateFunc, if set, is called to determine the rate for a given date
+	// instead of using Rate.
+	RateFunc func(date Date) (float64, error)
+}
+
+// rate returns the number of units of the custom currency per one unit of
+// its anchor on the given date, which must be finite and positive.
+func (c *CustomCurrency) rate(date Date) (float64, error) {
+	rate := c.Rate
+	if c.RateFunc != nil {
+		var err error
+		if rate, err = c.RateFunc(date); err != nil {
+			return 0, err
+		}
+	}
+	if !validRate(rate) {
+		return 0, fmt.Errorf("invalid rate %v on %s", rate, date)
+	}
+	return rate, nil
+}
+
+// validRate reports whether rate is finite and positive.
+func validRate(rate float64) bool {
+	return rate > 0 && !math.IsInf(rate, 1)
+}
+
+// CustomCurrenciesService holds user-defined currencies, which are resolved
+// locally and never sent to OXR.
+type CustomCurrenciesService struct {
+	mu         sync.RWMutex
+	currencies map[string]CustomCurrency
+}
+
+// NewCustomCurrenciesService creates a new handler for this service.
+func NewCustomCurrenciesService() *CustomCurrenciesService {
+	return &CustomCurrenciesService{
+		currencies: map[string]CustomCurrency{},
+	}
+}
+
+// Register adds (or replaces) a custom currency. Its code mustn't be a real
+// currency, and its anchor must be one, so custom currencies are never
+// anchored to each other in either direction.
+func (s *CustomCurrenciesService) Register(currency CustomCurrency) error {
+	if currency.Code == "" || currency.Anchor == "" {
+		return errors.New("custom currency code and anchor must be passed")
+	}
+	if currency.RateFunc == nil && !validRate(currency.Rate) {
+		return errors.New("custom currency rate must be positive and finite")
+	}
+
+	if isRealCurrency(currency.Code) {
+		return fmt.Errorf("%w: %s", ErrRealCurrency, currency.Code)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.currencies[currency.Anchor]; ok {
+		return fmt.Errorf("custom currency %s can't be anchored to custom currency %s", currency.Code, currency.Anchor)
+	}
+	if !isRealCurrency(currency.Anchor) {
+		return fmt.Errorf("custom currency %s must be anchored to a real currency, not %s", currency.Code, currency.Anchor)
+	}
+	s.currencies[currency.Code] = currency
+	return nil
+}
+
+// Unregister removes a custom currency.
+func (s *CustomCurrenciesService) Unregister(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.currencies, code)
+}
+
+// Lookup returns the custom currency registered for code, if any.
+func (s *CustomCurrenciesService) Lookup(code string) (CustomCurrency, bool) {
+	if s == nil {
+		return CustomCurrency{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	currency, ok := s.currencies[code]
+	return currency, ok
+}
+
+// List returns all registered custom currencies, sorted by code.
+func (s *CustomCurrenciesService) List() []CustomCurrency {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]CustomCurrency, 0, len(s.currencies))
+	for _, currency := range s.currencies {
+		list = append(list, currency)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// apply returns rsp with a rate for every custom currency whose anchor is in
+// the table. The given response is never modified; a copy is returned if
+// anything was added. Custom currencies whose rate can't be determined are
+// reported through warn and left out.
+func (s *CustomCurrenciesService) apply(rsp *RateResponse, date Date, warn func(error)) *RateResponse {
+	if s == nil || rsp == nil {
+		return rsp
+	}
+
+	var out *RateResponse
+	for _, currency := range s.List() {
+		anchorRate, ok := rsp.Rates[currency.Anchor]
+		if currency.Anchor == rsp.Base {
+			anchorRate, ok = 1, true
+		}
+		if !ok {
+			anchorRate, ok = fixedRate(rsp, currency.Anchor, date)
+		}
+		if !ok {
+			continue
+		}
+
+		rate, err := currency.rate(date)
+		if err != nil {
+			warn(fmt.Errorf("custom currency %s: %w", currency.Code, err))
+			continue
+		}
+
+		if out == nil {
+			out = rsp.clone()
+		}
+		out.Rates[currency.Code] = anchorRate * rate
+	}
+
+	if out == nil {
+		return rsp
+	}
+	return out
+}
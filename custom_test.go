@@ -0,0 +1,173 @@
+package dinero
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestCustomCurrencies_Rates will test custom currencies are resolved against their anchor.
+func TestCustomCurrencies_Rates(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"USD": 1,
+		"AUD": 1.25,
+	}))
+
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "PTS",
+		Name:   "Loyalty Points",
+		Anchor: "AUD",
+		Rate:   100,
+	})).Should(BeNil())
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "CRD",
+		Name:   "Game Credits",
+		Anchor: "USD",
+		RateFunc: func(date Date) (float64, error) {
+			return 20, nil
+		},
+	})).Should(BeNil())
+
+	// Custom currencies can't be anchored to each other.
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "XXX",
+		Anchor: "PTS",
+		Rate:   1,
+	})).ShouldNot(BeNil())
+
+	// Nor can they shadow real currencies, or be anchored to unknown ones.
+	err := client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "USD",
+		Anchor: "AUD",
+		Rate:   1,
+	})
+	Expect(errors.Is(err, ErrRealCurrency)).Should(BeTrue())
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "DEM",
+		Anchor: "EUR",
+		Rate:   1,
+	})).ShouldNot(BeNil())
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "XXX",
+		Anchor: "ABC",
+		Rate:   1,
+	})).ShouldNot(BeNil())
+	_, ok := client.CustomCurrencies.Lookup("USD")
+	Expect(ok).Should(BeFalse())
+
+	rate, err := client.Rates.Get("PTS")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(125.0))
+
+	rsp, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Rates).Should(HaveKeyWithValue("CRD", 20.0))
+
+	// 1000 points = 10 AUD = 8 USD = 160 credits.
+	amount, err := client.Rates.Convert(1000, "PTS", "CRD")
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 160, 1e-9))
+
+	amount, err = client.HistoricalRates.Convert(1000, "PTS", "USD", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 8, 1e-9))
+}
+
+// TestCustomCurrencies_Base will test custom currencies are never sent to OXR as a base.
+func TestCustomCurrencies_Base(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	requests := 0
+	client := newTestClient(t, "PTS", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "PTS",
+		Anchor: "AUD",
+		Rate:   100,
+	})).Should(BeNil())
+
+	_, err := client.Rates.List()
+	Expect(errors.Is(err, ErrCustomBase)).Should(BeTrue())
+
+	_, err = client.HistoricalRates.List(time.Now())
+	Expect(errors.Is(err, ErrCustomBase)).Should(BeTrue())
+
+	Expect(requests).Should(Equal(0))
+}
+
+// TestCustomCurrencies_List will test custom currencies can be included when listing currencies.
+func TestCustomCurrencies_List(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"AUD": "Australian Dollar"}`))
+	}))
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "PTS",
+		Name:   "Loyalty Points",
+		Anchor: "AUD",
+		Rate:   100,
+	})).Should(BeNil())
+
+	rsp, err := client.Currencies.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp).Should(HaveLen(1))
+
+	client.Currencies.SetIncludeCustom(true)
+	rsp, err = client.Currencies.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp).Should(ContainElement(&CurrencyResponse{
+		Code: "PTS",
+		Name: "Loyalty Points",
+	}))
+}
+
+// TestCustomCurrencies_InvalidRate will test custom currencies whose rate isn't finite and positive are left out.
+func TestCustomCurrencies_InvalidRate(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"USD": 1,
+		"AUD": 1.25,
+	}))
+	warnings := 0
+	client.OnWarning = func(err error) {
+		warnings++
+	}
+
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "PTS",
+		Anchor: "AUD",
+		Rate:   math.Inf(1),
+	})).ShouldNot(BeNil())
+
+	for i, rate := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		rate := rate
+		Expect(client.CustomCurrencies.Register(CustomCurrency{
+			Code:   "CRD",
+			Anchor: "USD",
+			RateFunc: func(date Date) (float64, error) {
+				return rate, nil
+			},
+		})).Should(BeNil())
+
+		_, err := client.Rates.Get("CRD")
+		Expect(err).Should(Equal(ErrRatesNotFound))
+
+		_, err = client.Rates.Convert(100, "CRD", "AUD")
+		Expect(err).Should(Equal(ErrRatesNotFound))
+		Expect(warnings).Should(BeNumerically(">", i))
+	}
+}
@@ -0,0 +1,113 @@
+package dinero
+
+import (
+	"fmt"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Date is a civil (calendar) date, with no time of day or time zone. OXR
+// publishes historical rates per UTC day, so this is what rates are keyed by.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDate returns the date for the given year, month and day, normalising
+// out-of-range values (e.g. January 32nd becomes February 1st).
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the date t falls on in its own location. Convert t with
+// t.In first to get the date in another location.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date in the form 2006-01-02.
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return DateOf(t), nil
+}
+
+// String returns the date in the form 2006-01-02.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// In returns the start of the date in the given location.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// AddDays returns the date n days after d (or before, if n is negative).
+func (d Date) AddDays(n int) Date {
+	return NewDate(d.Year, d.Month, d.Day+n)
+}
+
+// Before reports whether d is before other.
+func (d Date) Before(other Date) bool {
+	return d.compare(other) < 0
+}
+
+// After reports whether d is after other.
+func (d Date) After(other Date) bool {
+	return d.compare(other) > 0
+}
+
+// IsZero reports whether d is the zero date.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// MarshalText implements encoding.TextMarshaler, so dates are written to JSON
+// as 2006-01-02.
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Date) UnmarshalText(data []byte) error {
+	parsed, err := ParseDate(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Date) compare(other Date) int {
+	switch {
+	case d.Year != other.Year:
+		return d.Year - other.Year
+	case d.Month != other.Month:
+		return int(d.Month) - int(other.Month)
+	}
+	return d.Day - other.Day
+}
+
+// location returns the time zone the client decides which day an instant
+// falls on in, defaulting to UTC.
+func (c *Client) location() *time.Location {
+	if c == nil || c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// dateOf returns the date t falls on in the client's time zone.
+func (c *Client) dateOf(t time.Time) Date {
+	return DateOf(t.In(c.location()))
+}
+
+// today returns the current date in the client's time zone.
+func (c *Client) today() Date {
+	return c.dateOf(time.Now())
+}
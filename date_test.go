@@ -0,0 +1,82 @@
+package dinero
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestDate will test parsing, formatting and comparing civil dates.
+func TestDate(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	date, err := ParseDate("2024-03-31")
+	Expect(err).Should(BeNil())
+	Expect(date).Should(Equal(NewDate(2024, time.March, 31)))
+	Expect(date.String()).Should(Equal("2024-03-31"))
+
+	Expect(date.AddDays(1)).Should(Equal(NewDate(2024, time.April, 1)))
+	Expect(date.AddDays(-31)).Should(Equal(NewDate(2024, time.February, 29)))
+	Expect(date.Before(date.AddDays(1))).Should(BeTrue())
+	Expect(date.After(date.AddDays(1))).Should(BeFalse())
+	Expect(Date{}.IsZero()).Should(BeTrue())
+
+	_, err = ParseDate("31/03/2024")
+	Expect(err).ShouldNot(BeNil())
+
+	// An instant's date depends on the location it's seen from.
+	sydney := time.FixedZone("AEDT", 11*60*60)
+	instant := time.Date(2024, time.March, 31, 20, 0, 0, 0, time.UTC)
+	Expect(DateOf(instant)).Should(Equal(date))
+	Expect(DateOf(instant.In(sydney))).Should(Equal(date.AddDays(1)))
+
+	data, err := json.Marshal(map[string]Date{"date": date})
+	Expect(err).Should(BeNil())
+	Expect(string(data)).Should(Equal(`{"date":"2024-03-31"}`))
+
+	var decoded map[string]Date
+	Expect(json.Unmarshal(data, &decoded)).Should(BeNil())
+	Expect(decoded["date"]).Should(Equal(date))
+}
+
+// TestClient_Location will test that historical requests and cache keys use the UTC day, or the client's Location.
+func TestClient_Location(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	var paths []string
+	handler := ratesHandler(map[string]float64{"AUD": 1.3})
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		handler.ServeHTTP(w, r)
+	}))
+
+	// 8am on the 1st in Sydney is still the 31st in UTC.
+	sydney := time.FixedZone("AEDT", 11*60*60)
+	instant := time.Date(2021, time.January, 1, 8, 0, 0, 0, sydney)
+
+	rsp, err := client.HistoricalRates.List(instant)
+	Expect(err).Should(BeNil())
+	Expect(rsp.Date).Should(Equal("2020-12-31"))
+
+	// The same day, however it's passed, is served from the cache.
+	_, err = client.HistoricalRates.ListOn(NewDate(2020, time.December, 31))
+	Expect(err).Should(BeNil())
+	_, err = client.HistoricalRates.List(instant.UTC())
+	Expect(err).Should(BeNil())
+	Expect(paths).Should(Equal([]string{"/api/historical/2020-12-31.json"}))
+
+	// With a business time zone, it's the 1st.
+	client.Location = sydney
+	rsp, err = client.HistoricalRates.List(instant.UTC())
+	Expect(err).Should(BeNil())
+	Expect(rsp.Date).Should(Equal("2021-01-01"))
+	Expect(paths).Should(Equal([]string{
+		"/api/historical/2020-12-31.json",
+		"/api/historical/2021-01-01.json",
+	}))
+}
@@ -9,38 +9,89 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
-
-	cache "github.com/patrickmn/go-cache"
 )
 
 const (
 	packageVersion = "0.8.0"
 	backendURL     = "https://openexchangerates.org"
 	userAgent      = "dinero/" + packageVersion
+	// defaultBaseCurrency is the base OXR uses when none is passed.
+	defaultBaseCurrency = "USD"
 )
 
 var (
 	// ErrRatesNotFound is returned if no rate can be found for a given currency code.
 	ErrRatesNotFound = errors.New("no rates found for code")
+	// ErrStaleRates is returned if the latest rates are older than the
+	// client's MaxAge.
+	ErrStaleRates = errors.New("rates are older than the maximum age")
+	// ErrNothingToRevalidate is returned if OXR answers that rates haven't
+	// changed, but the client no longer holds them.
+	ErrNothingToRevalidate = errors.New("rates not modified, but none are held")
 )
 
 // Client holds a connection to the OXR API.
 type Client struct {
 	// client is the HTTP client the package will use for requests.
 	client *http.Client
+	// middleware wraps the client's transport, outermost first.
+	middleware []Middleware
+	// wrapped is client with its transport wrapped in middleware, if any.
+	wrapped *http.Client
 	// AppID is the Open Exchange Rates application ID.
 	AppID string
+	// Keys, if set, supplies the app ID for each request in place of AppID,
+	// e.g. a KeyPool rotating through several.
+	Keys KeyProvider
 	// UserAgent is the UA for this package that all requests will use.
 	UserAgent string
 	// BackendURL is the base API endpoint at OXR.
 	BackendURL *url.URL
+	// OnWarning, if set, is called with any non-fatal problems found while
+	// resolving rates, e.g. a currency code that wasn't in use on the
+	// requested date.
+	OnWarning func(error)
+	// Store, if set, persists every rate table fetched from OXR.
+	Store RateStore
+	// Offline makes the client serve rates only from Store, never calling
+	// OXR.
+	Offline bool
+	// Location is the time zone used to decide which day a time.Time passed
+	// to the client falls on. OXR publishes rates per UTC day, so this
+	// defaults to UTC; set it to a business time zone to have e.g. an
+	// evening in Sydney use that day's rates.
+	Location *time.Location
+	// MaxAge, if set, is the oldest the latest rates may be, going by when
+	// OXR published them. Older rates are rejected with ErrStaleRates.
+	MaxAge time.Duration
+	// IncludeLegacy adds the legacy currencies replaced by the euro (e.g. DEM)
+	// to the rate tables List returns. Get and Convert resolve them either
+	// way.
+	IncludeLegacy bool
+	// Metrics, if set, is told about requests and the cache.
+	Metrics Metrics
+	// Tracer, if set, traces API calls, cache lookups and requests to OXR,
+	// within the context passed to the services' Context methods.
+	Tracer Tracer
+	// Logger, if set, logs requests, responses, cache events, refreshes and
+	// warnings, with the app ID redacted.
+	Logger Logger
+	// Limiter, if set, limits how often the client calls OXR.
+	Limiter *RateLimiter
+	// Quota, if set, keeps the client within a budget of its plan's request
+	// quota.
+	Quota *QuotaGuard
 
 	// Services used for communicating with the API.
-	Rates           *RatesService
-	HistoricalRates *HistoricalRatesService
-	Currencies      *CurrenciesService
-	Cache           *CacheService
+	Rates            *RatesService
+	HistoricalRates  *HistoricalRatesService
+	Currencies       *CurrenciesService
+	Usage            *UsageService
+	Cache            *CacheService
+	Overrides        *OverridesService
+	CustomCurrencies *CustomCurrenciesService
 }
 
 // NewClient creates a new Client with the appropriate connection details and
@@ -59,23 +110,42 @@ func NewClient(appID, baseCurrency string, expiry time.Duration) *Client {
 		AppID:      appID,
 	}
 
-	// Init a new store.
-	store := cache.New(expiry, 10*time.Minute)
-
 	// Init services.
 	c.Rates = NewRatesService(c, baseCurrency)
 	c.HistoricalRates = NewHistoricalRatesService(c, baseCurrency)
 	c.Currencies = NewCurrenciesService(c)
-	c.Cache = NewCacheService(c, store)
+	c.Usage = NewUsageService(c)
+	c.Cache = NewCacheService(c, expiry)
+	c.Overrides = NewOverridesService()
+	c.CustomCurrencies = NewCustomCurrenciesService()
 
 	return c
 }
 
+// SetHTTPClient will set the HTTP client used for requests, e.g. to set a
+// timeout or a custom transport. A nil client means http.DefaultClient.
+func (c *Client) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c.client = client
+	c.wrap()
+}
+
 // NewRequest creates an authenticated API request. A relative URL can be provided in urlPath,
 // which will be resolved to the BackendURL of the Client.
 func (c *Client) NewRequest(method, urlPath string, params url.Values, body interface{}) (*http.Request, error) {
+	appID, err := c.appID()
+	if err != nil {
+		return nil, err
+	}
+	return c.newRequest(method, urlPath, params, appID, body)
+}
+
+// newRequest is NewRequest, for the given app ID.
+func (c *Client) newRequest(method, urlPath string, params url.Values, appID string, body interface{}) (*http.Request, error) {
 	// make sure rendered URL is correct whether we have other params than app_id or not
-	params.Set("app_id", c.AppID)
+	params.Set("app_id", appID)
 	// Parse our URL.
 	rel, err := url.Parse(
 		fmt.Sprintf("/api/%s?%s", urlPath, params.Encode()),
@@ -125,13 +195,101 @@ func (c *Client) request(method string, rel *url.URL, body interface{}) (*http.R
 	return req, nil
 }
 
+// appID returns the app ID for the next request.
+func (c *Client) appID() (string, error) {
+	if c.Keys != nil {
+		return c.Keys.Key()
+	}
+	return c.AppID, nil
+}
+
+// maxKeyAttempts is the most app IDs a request is tried with when the client
+// has a KeyProvider.
+const maxKeyAttempts = 3
+
 // Do sends an API request and returns the API response. The API response is
 // JSON decoded and stored in 'v', or returned as an error if an API (if found).
+// The request is traced within its context. With a KeyProvider, requests
+// rejected for their app ID (or its quota) are retried with the next one.
 func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
-	resp, err := c.client.Do(req)
+	response, err := c.do(req, v)
+	for attempt := 1; attempt < maxKeyAttempts && c.Keys != nil && err != nil; attempt++ {
+		key := req.URL.Query().Get("app_id")
+		// Usage is per app ID, so is never retried with another.
+		if key == "" || endpointOf(req.URL.Path) == usageEndpoint {
+			break
+		}
+		if !isInvalidKey(err) && !isQuotaExhausted(err) {
+			break
+		}
+		next, keyErr := c.Keys.Key()
+		if keyErr != nil || next == key {
+			break
+		}
+
+		c.log(LevelInfo, "retrying with another app ID",
+			Field{Key: "endpoint", Value: endpointOf(req.URL.Path)},
+			Field{Key: "error", Value: err},
+		)
+		req = withAppID(req, next)
+		response, err = c.do(req, v)
+	}
+	return response, err
+}
+
+// withAppID returns a copy of req using a different app ID.
+func withAppID(req *http.Request, appID string) *http.Request {
+	out := req.Clone(req.Context())
+	query := out.URL.Query()
+	query.Set("app_id", appID)
+	out.URL.RawQuery = query.Encode()
+	return out
+}
+
+// do sends an API request once, as Do.
+func (c *Client) do(req *http.Request, v interface{}) (response *Response, err error) {
+	ctx, span := c.startSpan(req.Context(), "dinero.Do",
+		Attribute{Key: AttributeHTTPMethod, Value: req.Method},
+		Attribute{Key: AttributeEndpoint, Value: endpointOf(req.URL.Path)},
+	)
+	defer func() { endSpan(span, err) }()
+	req = req.WithContext(ctx)
+
+	endpoint := endpointOf(req.URL.Path)
+	if err := c.admit(req, endpoint); err != nil {
+		c.log(LevelWarn, "request refused",
+			Field{Key: "endpoint", Value: endpoint},
+			Field{Key: "error", Value: err},
+		)
+		return nil, err
+	}
+	c.log(LevelDebug, "request",
+		Field{Key: "method", Value: req.Method},
+		Field{Key: "url", Value: req.URL},
+	)
+
+	start := time.Now()
+	resp, err := c.httpClient().Do(req)
+	took := time.Since(start)
 	if err != nil {
+		c.observeRequest(req, 0, took)
+		// The HTTP client's errors include the URL, and so the app ID.
+		err = redactError(err)
+		c.log(LevelError, "request failed",
+			Field{Key: "endpoint", Value: endpoint},
+			Field{Key: "duration", Value: took},
+			Field{Key: "error", Value: err},
+		)
 		return nil, err
 	}
+	c.observeRequest(req, resp.StatusCode, took)
+	c.log(LevelDebug, "response",
+		Field{Key: "endpoint", Value: endpoint},
+		Field{Key: "status", Value: resp.StatusCode},
+		Field{Key: "duration", Value: took},
+		Field{Key: "header", Value: resp.Header},
+	)
+	span.SetAttributes(Attribute{Key: AttributeHTTPStatus, Value: resp.StatusCode})
 
 	defer func() {
 		if rerr := resp.Body.Close(); err == nil {
@@ -140,14 +298,33 @@ func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
 	}()
 
 	// Wrap our response.
-	response := &Response{Response: resp}
+	response = &Response{Response: resp}
 
 	// Check for any errors that may have occurred.
+	key := req.URL.Query().Get("app_id")
 	err = CheckResponse(resp)
+	if c.Keys != nil && key != "" {
+		c.Keys.Report(key, err)
+	}
 	if err != nil {
+		var errRsp *ErrorResponse
+		if errors.As(err, &errRsp) {
+			c.observeError(errRsp)
+			c.log(LevelWarn, "error response",
+				Field{Key: "endpoint", Value: endpoint},
+				Field{Key: "status", Value: resp.StatusCode},
+				Field{Key: "code", Value: errRsp.Message},
+				Field{Key: "description", Value: errRsp.Description},
+			)
+		}
 		return response, err
 	}
 
+	// Nothing to decode if what we hold hasn't changed.
+	if resp.StatusCode == http.StatusNotModified {
+		return response, nil
+	}
+
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
 			_, err = io.Copy(w, resp.Body)
@@ -160,6 +337,17 @@ func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
 				return nil, err
 			}
 		}
+		if usage, ok := v.(*UsageResponse); ok {
+			c.observeUsage(usage.Data.Usage)
+			if pool, ok := c.Keys.(*KeyPool); ok && key != "" {
+				pool.ReportUsage(key, usage.Data.Usage)
+			}
+			if c.Quota != nil && c.seedsQuota(key) {
+				if err := c.Quota.seed(usage.Data.Usage); err != nil {
+					c.warn(fmt.Errorf("saving quota: %w", err))
+				}
+			}
+		}
 
 	}
 
@@ -182,26 +370,176 @@ type ErrorResponse struct {
 	Description string `json:"description"`
 }
 
+// Error returns the status code and OXR's description of the error. It never
+// includes the request URL, which carries the app ID.
 func (r *ErrorResponse) Error() string {
-	return fmt.Sprintf("%d %v", r.Response.StatusCode, r.Description)
+	status := 0
+	if r.Response != nil {
+		status = r.Response.StatusCode
+	}
+	return fmt.Sprintf("%d %v", status, r.Description)
+}
+
+// RetryAfter returns how long the response asked to wait before retrying,
+// from its Retry-After header, or zero if it didn't say.
+func (r *ErrorResponse) RetryAfter() time.Duration {
+	if r.Response == nil {
+		return 0
+	}
+	return retryAfter(r.Response.Header.Get("Retry-After"), time.Now())
 }
 
 // CheckResponse checks the API response for errors. A response is considered an
-// error if it has a status code outside the 200 range. API error responses map
-// to ErrorResponse.
+// error if it has a status code outside the 200 range, other than 304 Not
+// Modified in answer to a conditional request. API error responses map to
+// ErrorResponse; responses whose body isn't an API error (e.g. an HTML page
+// from a proxy) do too, described by their status.
 func CheckResponse(r *http.Response) error {
 	if c := r.StatusCode; c >= 200 && c <= 299 {
 		return nil
 	}
+	if r.StatusCode == http.StatusNotModified && r.Request != nil && isConditional(r.Request) {
+		return nil
+	}
 
+	// Keep the app ID out of the request held by the error, in case it's
+	// logged.
+	if r.Request != nil {
+		req := *r.Request
+		req.URL = RedactURL(r.Request.URL)
+		r.Request = &req
+	}
 	errorResponse := &ErrorResponse{Response: r}
 
 	data, err := ioutil.ReadAll(r.Body)
 	if err == nil && len(data) > 0 {
-		err := json.Unmarshal(data, errorResponse)
-		if err != nil {
-			return err
+		if err := json.Unmarshal(data, errorResponse); err != nil {
+			errorResponse.ErrorCode = 0
+			errorResponse.Message = ""
+			errorResponse.Description = ""
 		}
 	}
+	if errorResponse.Description == "" {
+		errorResponse.Description = http.StatusText(r.StatusCode)
+	}
 	return errorResponse
 }
+
+// retryAfter parses a Retry-After header value, given in seconds or as an
+// HTTP date, returning zero if it's missing, invalid or in the past.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// decorate layers fixed conversion factors, custom currencies and then any
+// overrides on top of the rates fetched for the given date.
+func (c *Client) decorate(rsp *RateResponse, date Date) *RateResponse {
+	rsp = applyFixedRates(rsp, date, c.IncludeLegacy)
+	rsp = c.CustomCurrencies.apply(rsp, date, c.warn)
+	return c.Overrides.apply(rsp, date)
+}
+
+// lookup returns the rate for code from rsp, resolving currencies that were
+// redenominated to whichever code was in use on the given date, and those
+// with a fixed conversion factor from their anchor.
+func (c *Client) lookup(rsp *RateResponse, code string, date Date) (*float64, error) {
+	return lookupRate(rsp, code, date, c.warn)
+}
+
+// lookupRate is Client.lookup, reporting redenominated codes to warn if it
+// isn't nil.
+func lookupRate(rsp *RateResponse, code string, date Date, warn func(error)) (*float64, error) {
+	if code == rsp.Base {
+		single := 1.0
+		return &single, nil
+	}
+
+	if active, factor := ResolveCode(code, date); active != code {
+		if warn != nil {
+			warn(&CodeNotActiveError{Code: code, Active: active, Date: date})
+		}
+		if rate, ok := rsp.Rates[active]; ok {
+			single := rate * factor
+			return &single, nil
+		}
+	}
+
+	if single, ok := rsp.Rates[code]; ok {
+		return &single, nil
+	}
+	if single, ok := fixedRate(rsp, code, date); ok {
+		return &single, nil
+	}
+	return nil, ErrRatesNotFound
+}
+
+// checkAge returns an error if rsp was published longer ago than the
+// client's MaxAge. Rates with no published time (e.g. some stored ones) go by
+// when they were fetched instead, and aren't checked if that's unknown too.
+func (c *Client) checkAge(rsp *RateResponse) error {
+	if c.MaxAge <= 0 {
+		return nil
+	}
+	if rsp.Timestamp != 0 {
+		if age := rsp.Age(); age > c.MaxAge {
+			return fmt.Errorf("%w: published %s, %s ago", ErrStaleRates, rsp.PublishedAt().Format(time.RFC3339), age.Truncate(time.Second))
+		}
+		return nil
+	}
+	if rsp.FetchedAt != 0 {
+		if age := time.Since(rsp.Fetched()); age > c.MaxAge {
+			return fmt.Errorf("%w: publication time unknown, fetched %s ago", ErrStaleRates, age.Truncate(time.Second))
+		}
+	}
+	return nil
+}
+
+func (c *Client) warn(err error) {
+	c.log(LevelWarn, "warning", Field{Key: "error", Value: err})
+	if c.OnWarning != nil {
+		c.OnWarning(err)
+	}
+}
+
+// checkBase returns an error if base is a custom currency, which must never be
+// sent to OXR.
+func (c *Client) checkBase(base string) error {
+	if _, ok := c.CustomCurrencies.Lookup(base); ok {
+		return fmt.Errorf("%w: %s", ErrCustomBase, base)
+	}
+	return nil
+}
+
+// convert converts an amount between two currencies on the given date using
+// the rates returned by get, preferring fixed conversion factors and
+// redenominations where they apply.
+func convert(amount float64, from, to string, date Date, get func(code string) (*float64, error)) (float64, error) {
+	if converted, err := ConvertFixed(amount, from, to, date); err == nil {
+		return converted, nil
+	}
+	if converted, err := Redenominate(amount, from, to); err == nil {
+		return converted, nil
+	}
+
+	fromRate, err := get(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := get(to)
+	if err != nil {
+		return 0, err
+	}
+	return amount / *fromRate * *toRate, nil
+}
@@ -1,11 +1,17 @@
 package dinero
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/mattevans/dinero/dinerotest"
 	. "github.com/onsi/gomega"
 )
 
@@ -51,3 +57,101 @@ func Test_NewRequest(t *testing.T) {
 		}
 	}
 }
+
+// newClient returns a client for the tests that exercise the OXR API. It
+// talks to the real API when OPEN_EXCHANGE_APP_ID is set, and to a fake
+// otherwise.
+func newClient(t *testing.T, base string) *Client {
+	if appID != "" {
+		return NewClient(appID, base, 1*time.Minute)
+	}
+
+	server := dinerotest.NewServer()
+	t.Cleanup(server.Close)
+
+	client := NewClient("12345", base, 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	return client
+}
+
+// newTestClient returns a client whose requests are served by the given
+// handler rather than the OXR API.
+func newTestClient(t *testing.T, base string, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("12345", base, 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	return client
+}
+
+// ratesHandler serves the given rate table for any latest or historical
+// request, reporting the requested base (or USD when none is passed).
+func ratesHandler(rates map[string]float64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := r.URL.Query().Get("base")
+		if base == "" {
+			base = defaultCurrency
+		}
+		_ = json.NewEncoder(w).Encode(&RateResponse{
+			Rates:     rates,
+			Base:      base,
+			Timestamp: 1640995200,
+		})
+	})
+}
+
+// TestCheckResponse will test mapping API and non-API error responses to ErrorResponse.
+func TestCheckResponse(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	var tests = []struct {
+		status      int
+		header      http.Header
+		body        string
+		message     string
+		description string
+		retryAfter  time.Duration
+	}{
+		{
+			status:      http.StatusUnauthorized,
+			body:        `{"error":true,"status":401,"message":"invalid_app_id","description":"Invalid App ID."}`,
+			message:     "invalid_app_id",
+			description: "Invalid App ID.",
+		},
+		{
+			status:      http.StatusBadGateway,
+			header:      http.Header{"Content-Type": []string{"text/html"}},
+			body:        "<html><body>502 Bad Gateway</body></html>",
+			description: "Bad Gateway",
+		},
+		{
+			status:      http.StatusTooManyRequests,
+			header:      http.Header{"Retry-After": []string{"120"}},
+			description: "Too Many Requests",
+			retryAfter:  2 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		rsp := &http.Response{
+			StatusCode: test.status,
+			Header:     test.header,
+			Body:       ioutil.NopCloser(strings.NewReader(test.body)),
+		}
+		if rsp.Header == nil {
+			rsp.Header = http.Header{}
+		}
+		err := CheckResponse(rsp)
+		errRsp, ok := err.(*ErrorResponse)
+		Expect(ok).Should(BeTrue())
+		Expect(errRsp.Message).Should(Equal(test.message))
+		Expect(errRsp.Description).Should(Equal(test.description))
+		Expect(errRsp.RetryAfter()).Should(Equal(test.retryAfter))
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	Expect(retryAfter(future, time.Now())).Should(BeNumerically("~", time.Hour, 2*time.Second))
+	Expect(retryAfter("soon", time.Now())).Should(BeZero())
+}
@@ -0,0 +1,160 @@
+// Package dineroprom exposes a dinero client's metrics to Prometheus. It's a
+// module of its own, so only applications that use it depend on the
+// Prometheus client library.
+package dineroprom
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mattevans/dinero"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram buckets.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	quotaRemainingDesc = prometheus.NewDesc(
+		"dinero_quota_remaining",
+		"Requests remaining in the quota, as last reported by usage.json.",
+		nil, nil,
+	)
+	ratesAgeDesc = prometheus.NewDesc(
+		"dinero_rates_age_seconds",
+		"Age of the latest rates held, by base, going by when OXR published them.",
+		[]string{"base"}, nil,
+	)
+)
+
+// Collector collects metrics about a client: requests to OXR and their
+// latency, cache hits and misses, errors returned by OXR, the request quota
+// remaining and the age of the latest rates held. Set it as a Client's
+// Metrics to collect them.
+//
+// Collector is a prometheus.Collector. Nothing is registered globally;
+// register it on whichever registry the application exposes.
+//
+//	collector := dineroprom.NewCollector()
+//	client.Metrics = collector
+//	prometheus.MustRegister(collector)
+type Collector struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	hits     *prometheus.CounterVec
+	misses   *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+
+	mu       sync.Mutex
+	quota    int64
+	hasQuota bool
+	// published holds when the latest rates held for each base were
+	// published.
+	published map[string]time.Time
+}
+
+var _ dinero.Metrics = (*Collector)(nil)
+
+// NewCollector creates a new, empty, set of metrics.
+func NewCollector() *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dinero_requests_total",
+			Help: "Requests made to OXR, by endpoint and status (error if none was received).",
+		}, []string{"endpoint", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dinero_request_duration_seconds",
+			Help:    "Latency of requests made to OXR, by endpoint and status.",
+			Buckets: latencyBuckets,
+		}, []string{"endpoint", "status"}),
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dinero_cache_hits_total",
+			Help: "Rate lookups served from the cache, by type (latest or historical).",
+		}, []string{"type"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dinero_cache_misses_total",
+			Help: "Rate lookups not served from the cache, by type (latest or historical).",
+		}, []string{"type"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dinero_upstream_errors_total",
+			Help: "Error responses from OXR, by error code.",
+		}, []string{"code"}),
+		published: map[string]time.Time{},
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.hits.Describe(ch)
+	c.misses.Describe(ch)
+	c.errors.Describe(ch)
+	ch <- quotaRemainingDesc
+	ch <- ratesAgeDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.hits.Collect(ch)
+	c.misses.Collect(ch)
+	c.errors.Collect(ch)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hasQuota {
+		ch <- prometheus.MustNewConstMetric(quotaRemainingDesc, prometheus.GaugeValue, float64(c.quota))
+	}
+	now := time.Now()
+	for base, published := range c.published {
+		ch <- prometheus.MustNewConstMetric(ratesAgeDesc, prometheus.GaugeValue, now.Sub(published).Seconds(), base)
+	}
+}
+
+// ObserveRequest implements dinero.Metrics.
+func (c *Collector) ObserveRequest(endpoint string, status int, took time.Duration) {
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	c.requests.WithLabelValues(endpoint, code).Inc()
+	c.duration.WithLabelValues(endpoint, code).Observe(took.Seconds())
+}
+
+// ObserveError implements dinero.Metrics.
+func (c *Collector) ObserveError(code string) {
+	c.errors.WithLabelValues(code).Inc()
+}
+
+// ObserveCache implements dinero.Metrics.
+func (c *Collector) ObserveCache(kind string, hit bool) {
+	if hit {
+		c.hits.WithLabelValues(kind).Inc()
+	} else {
+		c.misses.WithLabelValues(kind).Inc()
+	}
+}
+
+// ObserveUsage implements dinero.Metrics.
+func (c *Collector) ObserveUsage(usage dinero.Usage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.quota = usage.RequestsRemaining
+	c.hasQuota = true
+}
+
+// ObserveLatest implements dinero.Metrics.
+func (c *Collector) ObserveLatest(base string, published time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if published.After(c.published[base]) {
+		c.published[base] = published
+	}
+}
@@ -0,0 +1,79 @@
+package dineroprom
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero"
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestCollector will test collecting request, cache, error, quota and age metrics.
+func TestCollector(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetLatest(map[string]float64{"AUD": 1.25}, time.Now().Add(-time.Hour))
+	server.SetQuota(1000)
+
+	client := dinero.NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	collector := NewCollector()
+	client.Metrics = collector
+
+	_, err := client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	_, err = client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	_, err = client.HistoricalRates.GetOn("AUD", dinero.NewDate(2021, 1, 1))
+	Expect(err).Should(BeNil())
+	_, err = client.Usage.Get()
+	Expect(err).Should(BeNil())
+
+	server.Fail(dinerotest.Historical, dinerotest.ErrNotAvailable)
+	_, err = client.HistoricalRates.ListOn(dinero.NewDate(2020, 1, 1))
+	Expect(err).ShouldNot(BeNil())
+
+	// The metrics are registered on the caller's own registry.
+	registry := prometheus.NewPedanticRegistry()
+	Expect(registry.Register(collector)).Should(BeNil())
+	count, err := testutil.GatherAndCount(registry)
+	Expect(err).Should(BeNil())
+	Expect(count).Should(BeNumerically(">", 0))
+
+	Expect(testutil.ToFloat64(collector.requests.WithLabelValues("latest", "200"))).Should(Equal(1.0))
+	Expect(testutil.ToFloat64(collector.requests.WithLabelValues("historical", "200"))).Should(Equal(1.0))
+	Expect(testutil.ToFloat64(collector.requests.WithLabelValues("historical", "400"))).Should(Equal(1.0))
+	Expect(testutil.ToFloat64(collector.hits.WithLabelValues("latest"))).Should(Equal(2.0))
+	Expect(testutil.ToFloat64(collector.misses.WithLabelValues("latest"))).Should(Equal(1.0))
+	Expect(testutil.ToFloat64(collector.hits.WithLabelValues("historical"))).Should(Equal(1.0))
+	Expect(testutil.ToFloat64(collector.misses.WithLabelValues("historical"))).Should(Equal(2.0))
+	Expect(testutil.ToFloat64(collector.errors.WithLabelValues("not_available"))).Should(Equal(1.0))
+
+	Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP dinero_quota_remaining Requests remaining in the quota, as last reported by usage.json.
+# TYPE dinero_quota_remaining gauge
+dinero_quota_remaining 998
+`), "dinero_quota_remaining")).Should(BeNil())
+	Expect(testutil.CollectAndCount(collector, "dinero_request_duration_seconds")).Should(Equal(4))
+
+	families, err := registry.Gather()
+	Expect(err).Should(BeNil())
+	ages := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() == "dinero_rates_age_seconds" {
+			for _, metric := range family.GetMetric() {
+				ages[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+			}
+		}
+	}
+	Expect(ages).Should(HaveLen(1))
+	Expect(ages["USD"]).Should(BeNumerically("~", 3600, 60))
+}
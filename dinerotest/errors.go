@@ -0,0 +1,64 @@
+package dinerotest
+
+import "net/http"
+
+// Error is an error response, as documented by OXR.
+type Error struct {
+	Status      int    `json:"status"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+// The error responses documented by OXR.
+var (
+	ErrNotFound = &Error{
+		Status:      http.StatusNotFound,
+		Message:     "not_found",
+		Description: "Client requested a non-existent resource/route.",
+	}
+	ErrMissingAppID = &Error{
+		Status:      http.StatusUnauthorized,
+		Message:     "missing_app_id",
+		Description: "Client did not provide an App ID.",
+	}
+	ErrInvalidAppID = &Error{
+		Status:      http.StatusUnauthorized,
+		Message:     "invalid_app_id",
+		Description: "Client provided an invalid App ID.",
+	}
+	ErrNotAllowed = &Error{
+		Status:      http.StatusForbidden,
+		Message:     "not_allowed",
+		Description: "Changing the API `base` currency is available for Developer, Enterprise and Unlimited plan clients.",
+	}
+	ErrAccessRestricted = &Error{
+		Status:      http.StatusTooManyRequests,
+		Message:     "access_restricted",
+		Description: "Access restricted for repeated over-use, or the monthly request quota has been reached.",
+	}
+	ErrInvalidBase = &Error{
+		Status:      http.StatusBadRequest,
+		Message:     "invalid_base",
+		Description: "Client requested rates for an unsupported base currency.",
+	}
+	ErrNotAvailable = &Error{
+		Status:      http.StatusBadRequest,
+		Message:     "not_available",
+		Description: "Historical rates for the requested date are not available.",
+	}
+	ErrInvalidDate = &Error{
+		Status:      http.StatusBadRequest,
+		Message:     "invalid_date",
+		Description: "Client requested an invalid date.",
+	}
+)
+
+// write sends the error response.
+func (e *Error) write(w http.ResponseWriter) {
+	writeJSON(w, e.Status, map[string]interface{}{
+		"error":       true,
+		"status":      e.Status,
+		"message":     e.Message,
+		"description": e.Description,
+	})
+}
@@ -0,0 +1,274 @@
+package dinerotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Fault is a failure a FaultInjector can inject.
+type Fault int
+
+const (
+	// FaultReset fails the request as if the connection was reset.
+	FaultReset Fault = iota
+	// FaultTimeout fails the request with a timeout, after Delay or once the
+	// request's context is done, whichever is sooner.
+	FaultTimeout
+	// FaultRateLimit responds 429 with a Retry-After header.
+	FaultRateLimit
+	// FaultServerError responds with a 5xx (503 unless Status is set) and an
+	// HTML body, as a proxy or load balancer would.
+	FaultServerError
+	// FaultTruncated passes the request on, cutting the response body short.
+	FaultTruncated
+	// FaultWrongContentType passes the request on, replacing the response
+	// with an HTML page served 200 OK.
+	FaultWrongContentType
+	// FaultSlowBody passes the request on, delaying each read of the
+	// response body by Delay.
+	FaultSlowBody
+)
+
+// String returns the fault's name.
+func (f Fault) String() string {
+	switch f {
+	case FaultReset:
+		return "reset"
+	case FaultTimeout:
+		return "timeout"
+	case FaultRateLimit:
+		return "rate limit"
+	case FaultServerError:
+		return "server error"
+	case FaultTruncated:
+		return "truncated"
+	case FaultWrongContentType:
+		return "wrong content type"
+	case FaultSlowBody:
+		return "slow body"
+	}
+	return fmt.Sprintf("Fault(%d)", int(f))
+}
+
+// Rule is when, and how, a FaultInjector fails requests.
+type Rule struct {
+	// Endpoint limits the rule to requests for an endpoint, e.g. Latest. The
+	// rule matches all endpoints if empty.
+	Endpoint string
+	// Probability is the chance the rule fails a matching request, between
+	// 0 and 1. Zero means always.
+	Probability float64
+	// Times limits how many requests the rule fails. Zero means no limit.
+	Times int
+
+	Fault Fault
+	// Status is the status code for FaultServerError.
+	Status int
+	// RetryAfter is the Retry-After for FaultRateLimit, a minute if zero.
+	RetryAfter time.Duration
+	// Delay is how long FaultTimeout waits, and FaultSlowBody waits per read.
+	Delay time.Duration
+}
+
+// rule is a Rule and how many requests it has failed.
+type rule struct {
+	Rule
+	failed int
+}
+
+// FaultInjector is an http.RoundTripper that fails requests by rule, for
+// testing how the client copes with an unreliable network or API. The first
+// matching rule applies; requests no rule fails are passed on.
+//
+//	faults := dinerotest.NewFaultInjector(nil, 1)
+//	faults.Add(dinerotest.Rule{Endpoint: dinerotest.Latest, Fault: dinerotest.FaultReset, Times: 2})
+//	client.SetHTTPClient(&http.Client{Transport: faults})
+type FaultInjector struct {
+	next   http.RoundTripper
+	mu     sync.Mutex
+	rules  []*rule
+	random *rand.Rand
+}
+
+// NewFaultInjector creates a fault injector passing requests on with next, or
+// http.DefaultTransport if nil. The seed makes probabilistic rules
+// repeatable.
+func NewFaultInjector(next http.RoundTripper, seed int64) *FaultInjector {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FaultInjector{next: next, random: rand.New(rand.NewSource(seed))}
+}
+
+// Add will add a rule, after any already added.
+func (f *FaultInjector) Add(r Rule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &rule{Rule: r})
+}
+
+// Clear will remove all rules.
+func (f *FaultInjector) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := f.match(req)
+	if r == nil {
+		return f.next.RoundTrip(req)
+	}
+
+	switch r.Fault {
+	case FaultReset:
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	case FaultTimeout:
+		return nil, timeout(req, r.Delay)
+	case FaultRateLimit:
+		retry := r.RetryAfter
+		if retry <= 0 {
+			retry = time.Minute
+		}
+		body := new(bytes.Buffer)
+		_ = json.NewEncoder(body).Encode(map[string]interface{}{
+			"error":       true,
+			"status":      ErrAccessRestricted.Status,
+			"message":     ErrAccessRestricted.Message,
+			"description": ErrAccessRestricted.Description,
+		})
+		rsp := response(req, ErrAccessRestricted.Status, "application/json", body.Bytes())
+		rsp.Header.Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+		return rsp, nil
+	case FaultServerError:
+		status := r.Status
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		return response(req, status, "text/html", page(status)), nil
+	case FaultWrongContentType:
+		return response(req, http.StatusOK, "text/html", page(http.StatusOK)), nil
+	}
+
+	rsp, err := f.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch r.Fault {
+	case FaultTruncated:
+		body, err := ioutil.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = body[:len(body)/2]
+		rsp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		rsp.ContentLength = -1
+		rsp.Header.Del("Content-Length")
+	case FaultSlowBody:
+		rsp.Body = &slowBody{ReadCloser: rsp.Body, delay: r.Delay, done: req.Context().Done()}
+	}
+	return rsp, nil
+}
+
+// match returns the rule failing a request, if any.
+func (f *FaultInjector) match(req *http.Request) *rule {
+	endpoint, _ := route(req.URL.Path)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, r := range f.rules {
+		if r.Endpoint != "" && r.Endpoint != endpoint {
+			continue
+		}
+		if r.Times > 0 && r.failed >= r.Times {
+			continue
+		}
+		if r.Probability > 0 && f.random.Float64() >= r.Probability {
+			continue
+		}
+		r.failed++
+		return r
+	}
+	return nil
+}
+
+// timeoutError is a network timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// timeout waits for the delay, or the request's context, and returns a
+// timeout error, or the context's error if it ended first.
+func timeout(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// response builds a response to a request.
+func response(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// page returns an HTML page for a status, like those served by proxies.
+func page(status int) []byte {
+	text := strings.TrimSpace(fmt.Sprintf("%d %s", status, http.StatusText(status)))
+	return []byte("<html><head><title>" + text + "</title></head><body><h1>" + text + "</h1></body></html>\n")
+}
+
+// slowBody reads at most a few bytes at a time, waiting before each read.
+type slowBody struct {
+	io.ReadCloser
+	delay time.Duration
+	done  <-chan struct{}
+}
+
+// slowChunk is how many bytes a slowBody reads at a time.
+const slowChunk = 16
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-b.done:
+		return 0, &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+	}
+	if len(p) > slowChunk {
+		p = p[:slowChunk]
+	}
+	return b.ReadCloser.Read(p)
+}
@@ -0,0 +1,123 @@
+package dinerotest_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero"
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// TestFaultInjector will test injecting each fault into the client's requests.
+func TestFaultInjector(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	faults := dinerotest.NewFaultInjector(nil, 1)
+	client := func() *dinero.Client {
+		client := newClient(t, server, "USD")
+		client.SetHTTPClient(&http.Client{Transport: faults})
+		return client
+	}
+
+	// Connection resets.
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultReset, Times: 1})
+	_, err := client().Rates.List()
+	Expect(errors.Is(err, syscall.ECONNRESET)).Should(BeTrue())
+	_, err = client().Rates.List()
+	Expect(err).Should(BeNil())
+
+	// Timeouts, cut short by the request's context.
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultTimeout, Delay: time.Millisecond, Times: 1})
+	_, err = client().Rates.List()
+	var netErr net.Error
+	Expect(errors.As(err, &netErr)).Should(BeTrue())
+	Expect(netErr.Timeout()).Should(BeTrue())
+
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultTimeout, Delay: time.Hour, Times: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/latest.json?app_id=test", nil)
+	_, err = faults.RoundTrip(req)
+	Expect(errors.Is(err, context.DeadlineExceeded)).Should(BeTrue())
+
+	// Rate limits, with Retry-After.
+	faults.Add(dinerotest.Rule{Endpoint: dinerotest.Latest, Fault: dinerotest.FaultRateLimit, RetryAfter: 30 * time.Second, Times: 1})
+	_, err = client().Rates.List()
+	var errRsp *dinero.ErrorResponse
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Response.StatusCode).Should(Equal(http.StatusTooManyRequests))
+	Expect(errRsp.Message).Should(Equal("access_restricted"))
+	Expect(errRsp.RetryAfter()).Should(Equal(30 * time.Second))
+
+	// Server errors with HTML bodies.
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultServerError, Status: http.StatusBadGateway, Times: 1})
+	_, err = client().Rates.List()
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Response.StatusCode).Should(Equal(http.StatusBadGateway))
+	Expect(errRsp.Description).Should(Equal("Bad Gateway"))
+
+	// Truncated and non-JSON bodies fail to decode.
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultTruncated, Times: 1})
+	_, err = client().Rates.List()
+	Expect(err).ShouldNot(BeNil())
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultWrongContentType, Times: 1})
+	_, err = client().Rates.List()
+	Expect(err).ShouldNot(BeNil())
+
+	// Slow bodies.
+	faults.Add(dinerotest.Rule{Fault: dinerotest.FaultSlowBody, Delay: time.Millisecond, Times: 1})
+	start := time.Now()
+	_, err = client().Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(time.Since(start)).Should(BeNumerically(">=", 10*time.Millisecond))
+
+	// Every rule is spent.
+	_, err = client().Rates.List()
+	Expect(err).Should(BeNil())
+}
+
+// TestFaultInjector_Match will test matching rules by endpoint and probability.
+func TestFaultInjector_Match(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	faults := dinerotest.NewFaultInjector(nil, 1)
+	faults.Add(dinerotest.Rule{Endpoint: dinerotest.Historical, Fault: dinerotest.FaultServerError})
+	faults.Add(dinerotest.Rule{Endpoint: dinerotest.Latest, Fault: dinerotest.FaultReset, Probability: 0.5})
+
+	failed := 0
+	for i := 0; i < 100; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/latest.json?app_id=test", nil)
+		rsp, err := faults.RoundTrip(req)
+		if err != nil {
+			failed++
+			continue
+		}
+		rsp.Body.Close()
+	}
+	Expect(failed).Should(BeNumerically("~", 50, 15))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/historical/2021-01-01.json?app_id=test", nil)
+	rsp, err := faults.RoundTrip(req)
+	Expect(err).Should(BeNil())
+	Expect(rsp.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+
+	faults.Clear()
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/historical/2021-01-01.json?app_id=test", nil)
+	rsp, err = faults.RoundTrip(req)
+	Expect(err).Should(BeNil())
+	Expect(rsp.StatusCode).Should(Equal(http.StatusOK))
+}
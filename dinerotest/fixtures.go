@@ -0,0 +1,32 @@
+package dinerotest
+
+// defaultRates are the latest rates served per USD until replaced with
+// SetLatest.
+var defaultRates = map[string]float64{
+	"AUD": 1.3745,
+	"CAD": 1.2632,
+	"CHF": 0.9123,
+	"CNY": 6.3735,
+	"EUR": 0.8793,
+	"GBP": 0.7392,
+	"HKD": 7.7977,
+	"JPY": 115.08,
+	"NZD": 1.4633,
+	"SGD": 1.3486,
+}
+
+// defaultCurrencies are the currencies served until replaced with
+// SetCurrencies.
+var defaultCurrencies = map[string]string{
+	"AUD": "Australian Dollar",
+	"CAD": "Canadian Dollar",
+	"CHF": "Swiss Franc",
+	"CNY": "Chinese Yuan",
+	"EUR": "Euro",
+	"GBP": "British Pound Sterling",
+	"HKD": "Hong Kong Dollar",
+	"JPY": "Japanese Yen",
+	"NZD": "New Zealand Dollar",
+	"SGD": "Singapore Dollar",
+	"USD": "United States Dollar",
+}
@@ -0,0 +1,162 @@
+package dinerotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mattevans/dinero/internal/redact"
+)
+
+// Mode is whether a Recorder records or replays.
+type Mode int
+
+const (
+	// Replay serves recorded responses, failing any request without one.
+	Replay Mode = iota
+	// Record passes requests on, recording the responses.
+	Record
+)
+
+// ErrNoRecording is returned by a replaying Recorder for a request it has no
+// recording of.
+var ErrNoRecording = errors.New("dinerotest: no recording for request")
+
+// Exchange is a recorded request and response, as stored in a golden file.
+type Exchange struct {
+	Method string `json:"method"`
+	// URL is the request URL, with the app ID scrubbed.
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records exchanges with OXR to golden
+// files in a directory, one per distinct request, or replays them. App IDs
+// are scrubbed from recordings, and ignored when matching requests; headers
+// carrying credentials or cookies aren't recorded.
+//
+//	recorder := dinerotest.NewRecorder(dinerotest.Replay, "testdata", nil)
+//	client.SetHTTPClient(&http.Client{Transport: recorder})
+type Recorder struct {
+	mode Mode
+	dir  string
+	next http.RoundTripper
+	mu   sync.Mutex
+}
+
+// NewRecorder creates a recorder for the golden files in dir. When recording,
+// requests are sent with next, or http.DefaultTransport if nil.
+func NewRecorder(mode Mode, dir string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{mode: mode, dir: dir, next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == Record {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	rsp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// Scrub the app ID wherever it appears, and leave out credentials and
+	// cookies.
+	appID := req.URL.Query().Get("app_id")
+	exchange := &Exchange{
+		Method:     req.Method,
+		URL:        redact.URL(req.URL).String(),
+		StatusCode: rsp.StatusCode,
+		Header:     redact.Header(rsp.Header, appID),
+		Body:       redact.String(string(body), appID),
+	}
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(r.path(req), data, 0644); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	data, err := ioutil.ReadFile(r.path(req))
+	r.mu.Unlock()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoRecording, req.Method, redact.URL(req.URL))
+		}
+		return nil, err
+	}
+
+	exchange := &Exchange{}
+	if err := json.Unmarshal(data, exchange); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+		StatusCode:    exchange.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        exchange.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(exchange.Body)),
+		ContentLength: int64(len(exchange.Body)),
+		Request:       req,
+	}, nil
+}
+
+// unsafeChars are replaced in golden file names.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._=-]+`)
+
+// path returns the golden file for a request, named for its method, path and
+// query (without the app ID).
+func (r *Recorder) path(req *http.Request) string {
+	query := req.URL.Query()
+	query.Del("app_id")
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	name := req.Method + " " + strings.TrimPrefix(req.URL.Path, "/api/")
+	for _, key := range keys {
+		name += " " + key + "=" + strings.Join(query[key], ",")
+	}
+	return filepath.Join(r.dir, unsafeChars.ReplaceAllString(name, "_")+".json")
+}
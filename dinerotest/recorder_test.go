@@ -0,0 +1,77 @@
+package dinerotest_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero"
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// TestRecorder will test recording exchanges to golden files and replaying them.
+func TestRecorder(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	dir := t.TempDir()
+	server := dinerotest.NewServer()
+	server.SetLatest(map[string]float64{"AUD": 1.25}, time.Time{})
+
+	// Record against the server.
+	client := dinero.NewClient("secret-app-id", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	withCookies := dinero.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		rsp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil {
+			rsp.Header.Set("Set-Cookie", "session=s3cr3t")
+			rsp.Header.Set("Link", "</api/usage.json?app_id=secret-app-id>")
+		}
+		return rsp, err
+	})
+	client.SetHTTPClient(&http.Client{Transport: dinerotest.NewRecorder(dinerotest.Record, dir, withCookies)})
+
+	rate, err := client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.25))
+	usage, err := client.Usage.Get()
+	Expect(err).Should(BeNil())
+	Expect(usage.Data.AppID).Should(Equal("secret-app-id"))
+	server.Close()
+
+	// The app ID and cookies never reach the golden files.
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	Expect(err).Should(BeNil())
+	Expect(files).Should(HaveLen(2))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		Expect(err).Should(BeNil())
+		Expect(string(data)).ShouldNot(ContainSubstring("secret-app-id"))
+		Expect(string(data)).ShouldNot(ContainSubstring("s3cr3t"))
+		Expect(string(data)).ShouldNot(ContainSubstring("Set-Cookie"))
+		Expect(string(data)).Should(ContainSubstring("REDACTED"))
+	}
+
+	// Replay with the server gone, and a different app ID.
+	replay := dinero.NewClient("another-app-id", "USD", 1*time.Minute)
+	replay.BackendURL, _ = url.Parse(server.URL)
+	replay.SetHTTPClient(&http.Client{Transport: dinerotest.NewRecorder(dinerotest.Replay, dir, nil)})
+
+	rate, err = replay.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.25))
+	usage, err = replay.Usage.Get()
+	Expect(err).Should(BeNil())
+	Expect(usage.Data.AppID).Should(Equal("REDACTED"))
+
+	// Requests without a recording fail.
+	_, err = replay.HistoricalRates.ListOn(dinero.NewDate(2021, 1, 1))
+	Expect(errors.Is(err, dinerotest.ErrNoRecording)).Should(BeTrue())
+	Expect(strings.Contains(err.Error(), "app_id=REDACTED")).Should(BeTrue())
+}
@@ -0,0 +1,534 @@
+// Package dinerotest provides an in-process fake of the Open Exchange Rates
+// API, for testing code that uses dinero without a real app ID or network
+// access.
+//
+//	server := dinerotest.NewServer()
+//	defer server.Close()
+//
+//	client := dinero.NewClient("test", "USD", time.Minute)
+//	client.BackendURL, _ = url.Parse(server.URL)
+package dinerotest
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The endpoints the server implements, for use with Fail.
+const (
+	Latest     = "latest"
+	Historical = "historical"
+	Currencies = "currencies"
+	Convert    = "convert"
+	TimeSeries = "time-series"
+	Usage      = "usage"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	disclaimer = "Usage subject to terms: https://openexchangerates.org/terms"
+	license    = "https://openexchangerates.org/license"
+)
+
+// firstDate is the earliest date OXR has historical rates for.
+var firstDate = time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	// Endpoint is the endpoint requested, e.g. Latest, or empty if the path
+	// didn't match one.
+	Endpoint string
+	Path     string
+	Query    url.Values
+	Header   http.Header
+}
+
+// Server is a fake OXR API. Its rates are held against USD and rebased for
+// any other base requested.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	appIDs     map[string]bool
+	timestamp  time.Time
+	latest     map[string]float64
+	historical map[string]map[string]float64
+	currencies map[string]string
+	failures   map[string]*Error
+	latency    time.Duration
+	quota      int64
+	used       int64
+	limit      int
+	window     time.Duration
+	started    time.Time
+	count      int
+	requests   []Request
+}
+
+// NewServer starts a fake OXR API serving the default fixtures. Close it when
+// done.
+func NewServer() *Server {
+	s := &Server{
+		latest:     copyRates(defaultRates),
+		historical: map[string]map[string]float64{},
+		currencies: map[string]string{},
+		failures:   map[string]*Error{},
+		quota:      -1,
+	}
+	for code, name := range defaultCurrencies {
+		s.currencies[code] = name
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// SetAppID will set the only app ID the server accepts. By default, any app
+// ID is accepted, but one must be passed.
+func (s *Server) SetAppID(appID string) {
+	if appID == "" {
+		s.SetAppIDs()
+		return
+	}
+	s.SetAppIDs(appID)
+}
+
+// SetAppIDs will set the app IDs the server accepts, or accept any if none
+// are given.
+func (s *Server) SetAppIDs(appIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appIDs = nil
+	if len(appIDs) > 0 {
+		s.appIDs = map[string]bool{}
+		for _, appID := range appIDs {
+			s.appIDs[appID] = true
+		}
+	}
+}
+
+// SetLatest will set the latest rates, per USD, and when they were published.
+// A zero time means the start of the current hour.
+func (s *Server) SetLatest(rates map[string]float64, published time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latest = copyRates(rates)
+	s.timestamp = published
+}
+
+// SetHistorical will set the rates, per USD, for the date (YYYY-MM-DD). Dates
+// without rates set are served the latest rates.
+func (s *Server) SetHistorical(date string, rates map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.historical[date] = copyRates(rates)
+}
+
+// SetCurrencies will set the currency codes and names served.
+func (s *Server) SetCurrencies(currencies map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.currencies = map[string]string{}
+	for code, name := range currencies {
+		s.currencies[code] = name
+	}
+}
+
+// SetQuota will set the number of requests allowed in total, after which
+// requests fail with ErrAccessRestricted. A negative quota, the default, is
+// unlimited. Requests for usage and currencies don't count.
+func (s *Server) SetQuota(quota int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quota = quota
+}
+
+// SetRateLimit will limit the server to n requests per period, failing any
+// more with ErrAccessRestricted and a Retry-After header. Zero turns the
+// limit off.
+func (s *Server) SetRateLimit(n int, per time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit, s.window = n, per
+	s.started, s.count = time.Time{}, 0
+}
+
+// SetLatency will delay every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// Fail will make every request to endpoint fail with err, until cleared by
+// passing a nil err.
+func (s *Server) Fail(endpoint string, err *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.failures, endpoint)
+		return
+	}
+	s.failures[endpoint] = err
+}
+
+// Requests will return the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	endpoint, params := route(r.URL.Path)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method:   r.Method,
+		Endpoint: endpoint,
+		Path:     r.URL.Path,
+		Query:    r.URL.Query(),
+		Header:   r.Header.Clone(),
+	})
+	latency := s.latency
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if endpoint == "" {
+		ErrNotFound.write(w)
+		return
+	}
+	if err := s.admit(w, endpoint, r.URL.Query().Get("app_id")); err != nil {
+		err.write(w)
+		return
+	}
+
+	query := r.URL.Query()
+	switch endpoint {
+	case Latest:
+		s.serveRates(w, query, "")
+	case Historical:
+		s.serveRates(w, query, params[0])
+	case Currencies:
+		s.serveCurrencies(w)
+	case Convert:
+		s.serveConvert(w, params)
+	case TimeSeries:
+		s.serveTimeSeries(w, query)
+	case Usage:
+		s.serveUsage(w, query.Get("app_id"))
+	}
+}
+
+// admit checks the app ID, limits and injected failures for a request to
+// endpoint, counting it towards the quota.
+func (s *Server) admit(w http.ResponseWriter, endpoint, appID string) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if endpoint != Currencies {
+		switch {
+		case appID == "":
+			return ErrMissingAppID
+		case s.appIDs != nil && !s.appIDs[appID]:
+			return ErrInvalidAppID
+		}
+	}
+
+	if s.limit > 0 {
+		now := time.Now()
+		if now.Sub(s.started) >= s.window {
+			s.started, s.count = now, 0
+		}
+		if s.count >= s.limit {
+			retry := s.started.Add(s.window).Sub(now)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			return ErrAccessRestricted
+		}
+		s.count++
+	}
+
+	if endpoint != Currencies && endpoint != Usage {
+		if s.quota >= 0 && s.used >= s.quota {
+			return ErrAccessRestricted
+		}
+		s.used++
+	}
+
+	return s.failures[endpoint]
+}
+
+func (s *Server) serveRates(w http.ResponseWriter, query url.Values, date string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rates, timestamp, err := s.ratesOn(date)
+	if err != nil {
+		err.write(w)
+		return
+	}
+	base, rates, err := rebase(rates, query.Get("base"), query.Get("symbols"))
+	if err != nil {
+		err.write(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"disclaimer": disclaimer,
+		"license":    license,
+		"timestamp":  timestamp,
+		"base":       base,
+		"rates":      rates,
+	})
+}
+
+func (s *Server) serveCurrencies(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.currencies)
+}
+
+func (s *Server) serveConvert(w http.ResponseWriter, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, err := strconv.ParseFloat(params[0], 64)
+	if err != nil {
+		ErrNotFound.write(w)
+		return
+	}
+	from, to := strings.ToUpper(params[1]), strings.ToUpper(params[2])
+
+	rates, timestamp, _ := s.ratesOn("")
+	_, rebased, rerr := rebase(rates, from, to)
+	if rerr != nil {
+		rerr.write(w)
+		return
+	}
+	rate, ok := rebased[to]
+	if !ok {
+		ErrInvalidBase.write(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"disclaimer": disclaimer,
+		"license":    license,
+		"request": map[string]interface{}{
+			"query":  "/convert/" + strings.Join(params, "/"),
+			"amount": amount,
+			"from":   from,
+			"to":     to,
+		},
+		"meta": map[string]interface{}{
+			"timestamp": timestamp,
+			"rate":      rate,
+		},
+		"response": amount * rate,
+	})
+}
+
+func (s *Server) serveTimeSeries(w http.ResponseWriter, query url.Values) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, err := time.Parse(dateLayout, query.Get("start"))
+	if err != nil {
+		ErrInvalidDate.write(w)
+		return
+	}
+	end, err := time.Parse(dateLayout, query.Get("end"))
+	if err != nil || end.Before(start) {
+		ErrInvalidDate.write(w)
+		return
+	}
+
+	base := ""
+	series := map[string]map[string]float64{}
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		day := date.Format(dateLayout)
+		rates, _, rerr := s.ratesOn(day)
+		if rerr == nil {
+			base, rates, rerr = rebase(rates, query.Get("base"), query.Get("symbols"))
+		}
+		if rerr != nil {
+			rerr.write(w)
+			return
+		}
+		series[day] = rates
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"disclaimer": disclaimer,
+		"license":    license,
+		"start_date": query.Get("start"),
+		"end_date":   query.Get("end"),
+		"base":       base,
+		"rates":      series,
+	})
+}
+
+func (s *Server) serveUsage(w http.ResponseWriter, appID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining := s.quota - s.used
+	if s.quota < 0 {
+		remaining = -1
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": http.StatusOK,
+		"data": map[string]interface{}{
+			"app_id": appID,
+			"status": "active",
+			"plan": map[string]interface{}{
+				"name":             "Test",
+				"quota":            strconv.FormatInt(s.quota, 10) + " requests",
+				"update_frequency": "3600s",
+				"features": map[string]bool{
+					"base":         true,
+					"symbols":      true,
+					"experimental": true,
+					"time-series":  true,
+					"convert":      true,
+				},
+			},
+			"usage": map[string]interface{}{
+				"requests":           s.used,
+				"requests_quota":     s.quota,
+				"requests_remaining": remaining,
+				"days_elapsed":       1,
+				"days_remaining":     29,
+				"daily_average":      s.used,
+			},
+		},
+	})
+}
+
+// ratesOn returns the rates per USD for date (YYYY-MM-DD), or the latest
+// rates if date is empty, along with when they were published. The caller
+// must hold s.mu.
+func (s *Server) ratesOn(date string) (map[string]float64, int64, *Error) {
+	if date == "" {
+		published := s.timestamp
+		if published.IsZero() {
+			published = time.Now().Truncate(time.Hour)
+		}
+		return s.latest, published.Unix(), nil
+	}
+
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return nil, 0, ErrInvalidDate
+	}
+	if day.Before(firstDate) || day.After(time.Now()) {
+		return nil, 0, ErrNotAvailable
+	}
+
+	// Historical rates are published at the end of the day.
+	timestamp := day.AddDate(0, 0, 1).Unix() - 1
+	if rates, ok := s.historical[date]; ok {
+		return rates, timestamp, nil
+	}
+	return s.latest, timestamp, nil
+}
+
+// route returns the endpoint for an API path, along with any parameters in
+// the path.
+func route(path string) (string, []string) {
+	path = strings.TrimPrefix(path, "/api/")
+	switch {
+	case path == "latest.json":
+		return Latest, nil
+	case path == "currencies.json":
+		return Currencies, nil
+	case path == "time-series.json":
+		return TimeSeries, nil
+	case path == "usage.json":
+		return Usage, nil
+	case strings.HasPrefix(path, "historical/") && strings.HasSuffix(path, ".json"):
+		return Historical, []string{strings.TrimSuffix(strings.TrimPrefix(path, "historical/"), ".json")}
+	case strings.HasPrefix(path, "convert/"):
+		if params := strings.Split(strings.TrimPrefix(path, "convert/"), "/"); len(params) == 3 {
+			return Convert, params
+		}
+	}
+	return "", nil
+}
+
+// rebase converts rates per USD to rates per base (USD if empty), limited to
+// the comma-separated symbols if any are given.
+func rebase(rates map[string]float64, base, symbols string) (string, map[string]float64, *Error) {
+	if base == "" {
+		base = "USD"
+	}
+	divisor := 1.0
+	if base != "USD" {
+		var ok bool
+		if divisor, ok = rates[base]; !ok {
+			return "", nil, ErrInvalidBase
+		}
+	}
+
+	codes := []string{}
+	if symbols != "" {
+		for _, code := range strings.Split(symbols, ",") {
+			codes = append(codes, strings.ToUpper(strings.TrimSpace(code)))
+		}
+	} else {
+		for code := range rates {
+			codes = append(codes, code)
+		}
+		codes = append(codes, "USD")
+		sort.Strings(codes)
+	}
+
+	out := map[string]float64{}
+	for _, code := range codes {
+		if code == "USD" {
+			out[code] = 1 / divisor
+		} else if rate, ok := rates[code]; ok {
+			out[code] = rate / divisor
+		}
+	}
+	return base, out, nil
+}
+
+func copyRates(rates map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		out[code] = rate
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,161 @@
+package dinerotest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero"
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+func newClient(t *testing.T, server *dinerotest.Server, base string) *dinero.Client {
+	client := dinero.NewClient("test", base, 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	return client
+}
+
+// TestServer_Rates will test serving latest and historical fixtures in any base.
+func TestServer_Rates(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	published := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	server.SetLatest(map[string]float64{"AUD": 1.25, "NZD": 1.5}, published)
+	server.SetHistorical("2021-01-01", map[string]float64{"AUD": 1.3, "NZD": 1.4})
+
+	client := newClient(t, server, "AUD")
+	rsp, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Base).Should(Equal("AUD"))
+	Expect(rsp.Rates["USD"]).Should(Equal(0.8))
+	Expect(rsp.Rates["NZD"]).Should(Equal(1.2))
+	Expect(rsp.PublishedAt()).Should(Equal(published))
+
+	rate, err := client.HistoricalRates.GetOn("USD", dinero.NewDate(2021, 1, 1))
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically("~", 1/1.3, 1e-9))
+
+	// Dates without fixtures get the latest rates, but only up to today.
+	rate, err = client.HistoricalRates.GetOn("USD", dinero.NewDate(2021, 1, 2))
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(0.8))
+
+	_, err = client.HistoricalRates.ListOn(dinero.NewDate(1998, 12, 31))
+	var errRsp *dinero.ErrorResponse
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Message).Should(Equal("not_available"))
+
+	_, err = newClient(t, server, "XXX").Rates.List()
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Message).Should(Equal("invalid_base"))
+
+	currencies, err := client.Currencies.List()
+	Expect(err).Should(BeNil())
+	Expect(currencies).Should(ContainElement(&dinero.CurrencyResponse{Code: "NZD", Name: "New Zealand Dollar"}))
+}
+
+// TestServer_Endpoints will test the convert, time-series and usage endpoints.
+func TestServer_Endpoints(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetLatest(map[string]float64{"GBP": 0.5, "EUR": 0.8}, time.Time{})
+	server.SetQuota(10)
+
+	get := func(path string, v interface{}) int {
+		rsp, err := http.Get(server.URL + path)
+		Expect(err).Should(BeNil())
+		defer rsp.Body.Close()
+		Expect(json.NewDecoder(rsp.Body).Decode(v)).Should(BeNil())
+		return rsp.StatusCode
+	}
+
+	var converted struct {
+		Meta struct {
+			Rate float64 `json:"rate"`
+		} `json:"meta"`
+		Response float64 `json:"response"`
+	}
+	Expect(get("/api/convert/100/GBP/EUR?app_id=test", &converted)).Should(Equal(http.StatusOK))
+	Expect(converted.Meta.Rate).Should(Equal(1.6))
+	Expect(converted.Response).Should(Equal(160.0))
+
+	var series struct {
+		Base  string                        `json:"base"`
+		Rates map[string]map[string]float64 `json:"rates"`
+	}
+	Expect(get("/api/time-series.json?app_id=test&start=2021-01-01&end=2021-01-03&symbols=EUR", &series)).Should(Equal(http.StatusOK))
+	Expect(series.Base).Should(Equal("USD"))
+	Expect(series.Rates).Should(HaveLen(3))
+	Expect(series.Rates["2021-01-02"]).Should(Equal(map[string]float64{"EUR": 0.8}))
+
+	client := newClient(t, server, "")
+	usage, err := client.Usage.Get()
+	Expect(err).Should(BeNil())
+	Expect(usage.Data.Usage.Requests).Should(Equal(int64(2)))
+	Expect(usage.Data.Usage.RequestsQuota).Should(Equal(int64(10)))
+	Expect(usage.Data.Usage.RequestsRemaining).Should(Equal(int64(8)))
+}
+
+// TestServer_Failures will test simulating errors, rate limits and latency, and recording requests.
+func TestServer_Failures(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	var errRsp *dinero.ErrorResponse
+
+	// App IDs are checked.
+	server.SetAppID("secret")
+	_, err := newClient(t, server, "USD").Rates.List()
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Message).Should(Equal("invalid_app_id"))
+	server.SetAppID("")
+
+	// Injected errors apply to one endpoint until cleared.
+	server.Fail(dinerotest.Latest, dinerotest.ErrNotAllowed)
+	client := newClient(t, server, "USD")
+	_, err = client.Rates.List()
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Response.StatusCode).Should(Equal(http.StatusForbidden))
+	_, err = client.HistoricalRates.ListOn(dinero.NewDate(2021, 1, 1))
+	Expect(err).Should(BeNil())
+	server.Fail(dinerotest.Latest, nil)
+	_, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+
+	// Requests over the rate limit are restricted.
+	server.SetRateLimit(1, time.Minute)
+	_, err = newClient(t, server, "USD").Rates.List()
+	Expect(err).Should(BeNil())
+	_, err = newClient(t, server, "USD").Rates.List()
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Response.StatusCode).Should(Equal(http.StatusTooManyRequests))
+	Expect(errRsp.Response.Header.Get("Retry-After")).Should(Equal("60"))
+	server.SetRateLimit(0, 0)
+
+	// Responses can be slowed down.
+	server.SetLatency(20 * time.Millisecond)
+	start := time.Now()
+	_, err = newClient(t, server, "USD").Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(time.Since(start)).Should(BeNumerically(">=", 20*time.Millisecond))
+
+	requests := server.Requests()
+	Expect(requests).Should(HaveLen(7))
+	Expect(requests[0].Endpoint).Should(Equal(dinerotest.Latest))
+	Expect(requests[0].Query.Get("app_id")).Should(Equal("test"))
+	Expect(requests[2].Path).Should(Equal("/api/historical/2021-01-01.json"))
+}
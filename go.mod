@@ -2,7 +2,4 @@ module github.com/mattevans/dinero
 
 go 1.16
 
-require (
-	github.com/onsi/gomega v1.16.0
-	github.com/patrickmn/go-cache v2.1.0+incompatible
-)
+require github.com/onsi/gomega v1.16.0
@@ -1,9 +1,11 @@
 package dinero
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -14,7 +16,10 @@ const (
 // HistoricalRatesService handles historical rate request/responses.
 type HistoricalRatesService struct {
 	client       *Client
+	mu           sync.RWMutex
 	baseCurrency string
+	lookupMode   LookupMode
+	maxDistance  int
 }
 
 // NewHistoricalRatesService creates a new handler for this service.
@@ -35,81 +40,182 @@ type HistoricalRatesResponse struct {
 	Timestamp int64              `json:"timestamp"`
 }
 
-// List will fetch all the latest rates for the base currency either from the store or the OXR api.
+// List will fetch all the rates for the base currency for the day the given
+// time falls on in the client's Location (or the day the lookup mode falls
+// back to) either from the store or the OXR api. The response's Date reports
+// the day used.
 func (s *HistoricalRatesService) List(date time.Time) (*RateResponse, error) {
-	// If we have cached results, use them.
-	if results, ok := s.client.Cache.Get(s.baseCurrency, date); ok {
-		return results, nil
-	}
+	return s.ListOnContext(context.Background(), s.client.dateOf(date))
+}
 
-	// No cached results, go and fetch them.
-	if err := s.fetch(date); err != nil {
-		return nil, err
-	}
+// ListOn is like List, but for a calendar date.
+func (s *HistoricalRatesService) ListOn(date Date) (*RateResponse, error) {
+	return s.ListOnContext(context.Background(), date)
+}
+
+// ListContext is like List, tracing the call within ctx.
+func (s *HistoricalRatesService) ListContext(ctx context.Context, date time.Time) (*RateResponse, error) {
+	return s.ListOnContext(ctx, s.client.dateOf(date))
+}
 
-	return s.List(date)
+// ListOnContext is like ListOn, tracing the call within ctx.
+func (s *HistoricalRatesService) ListOnContext(ctx context.Context, date Date) (rsp *RateResponse, err error) {
+	ctx, span := s.client.startSpan(ctx, "dinero.HistoricalRates.List",
+		baseAttribute(s.GetBaseCurrency()),
+		Attribute{Key: AttributeDate, Value: date.String()},
+	)
+	defer func() { endSpan(span, err) }()
+
+	rsp, _, err = s.resolve(ctx, date, nil)
+	return rsp, err
 }
 
 // Get will fetch a single rate for a given currency either from the store or the OXR api.
 func (s *HistoricalRatesService) Get(code string, date time.Time) (*float64, error) {
-	// No code passed, let them know!
-	if code == "" {
-		return nil, errors.New("currency code must be passed")
+	return s.GetOnContext(context.Background(), code, s.client.dateOf(date))
+}
+
+// GetOn is like Get, but for a calendar date.
+func (s *HistoricalRatesService) GetOn(code string, date Date) (*float64, error) {
+	return s.GetOnContext(context.Background(), code, date)
+}
+
+// GetContext is like Get, tracing the call within ctx.
+func (s *HistoricalRatesService) GetContext(ctx context.Context, code string, date time.Time) (*float64, error) {
+	return s.GetOnContext(ctx, code, s.client.dateOf(date))
+}
+
+// GetOnContext is like GetOn, tracing the call within ctx.
+func (s *HistoricalRatesService) GetOnContext(ctx context.Context, code string, date Date) (rate *float64, err error) {
+	ctx, span := s.client.startSpan(ctx, "dinero.HistoricalRates.Get",
+		baseAttribute(s.GetBaseCurrency()),
+		Attribute{Key: AttributeDate, Value: date.String()},
+		Attribute{Key: AttributeSymbols, Value: code},
+	)
+	defer func() { endSpan(span, err) }()
+
+	found, err := s.lookupOn(ctx, code, date)
+	if err != nil {
+		return nil, err
 	}
+	return &found.Rate, nil
+}
 
+// list will fetch all the rates for the base currency for exactly the given
+// date either from the store or the OXR api.
+func (s *HistoricalRatesService) list(ctx context.Context, date Date) (*RateResponse, error) {
 	// If we have cached results, use them.
-	if results, ok := s.client.Cache.Get(s.baseCurrency, date); ok {
-		if single, ok := results.Rates[code]; ok {
-			return &single, nil
-		}
-		return nil, ErrRatesNotFound
+	if results, ok := s.client.Cache.getOn(ctx, s.GetBaseCurrency(), date); ok {
+		return s.client.decorate(results, date), nil
 	}
 
 	// No cached results, go and fetch them.
-	if err := s.fetch(date); err != nil {
-		return nil, err
+	if err := s.fetch(ctx, date); err != nil {
+		results, err := s.client.staleRates(s.GetBaseCurrency(), date, err)
+		if err != nil {
+			return nil, err
+		}
+		return s.client.decorate(results, date), nil
 	}
 
-	return s.Get(code, date)
+	return s.list(ctx, date)
+}
+
+// Convert will convert an amount between two currencies using the rates for the given date.
+func (s *HistoricalRatesService) Convert(amount float64, from, to string, date time.Time) (float64, error) {
+	return s.ConvertOn(amount, from, to, s.client.dateOf(date))
+}
+
+// ConvertOn is like Convert, but for a calendar date.
+func (s *HistoricalRatesService) ConvertOn(amount float64, from, to string, date Date) (float64, error) {
+	return convert(amount, from, to, date, func(code string) (*float64, error) {
+		return s.GetOn(code, date)
+	})
 }
 
 // GetBaseCurrency will return the baseCurrency.
 func (s *HistoricalRatesService) GetBaseCurrency() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	return s.baseCurrency
 }
 
 // SetBaseCurrency will set the base currency to be used for requests.
 func (s *HistoricalRatesService) SetBaseCurrency(base string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.baseCurrency = base
 }
 
-func (s *HistoricalRatesService) fetch(date time.Time) error {
+func (s *HistoricalRatesService) fetch(ctx context.Context, date Date) error {
+	base := s.GetBaseCurrency()
+	if err := s.client.checkBase(base); err != nil {
+		return err
+	}
+
+	// Offline, serve the stored rates for the date.
+	if s.client.Offline {
+		latest, err := s.client.loadStored(base, date)
+		if err != nil {
+			return err
+		}
+		latest.Date = date.String()
+		s.SetBaseCurrency(latest.Base)
+		s.client.Cache.storeOn(latest, date, nil)
+		return nil
+	}
+
+	// Don't repeat a request that's already failed for good.
+	if err := s.client.Cache.failure(base, date); err != nil {
+		return err
+	}
+
 	// Build request.
 	// add `base` query param if it is not empty
 	params := url.Values{}
-	if s.baseCurrency != "" {
-		params.Set("base", s.baseCurrency)
+	if base != "" {
+		params.Set("base", base)
 	}
 	request, err := s.client.NewRequest(
 		"GET",
-		fmt.Sprintf(historicalAPIPath, date.Format("2006-01-02")),
+		fmt.Sprintf(historicalAPIPath, date),
 		params,
 		nil,
 	)
 	if err != nil {
 		return err
 	}
+	request = request.WithContext(ctx)
+	validated := s.client.Cache.validate(request, base, date)
 
 	// Make request
+	s.client.log(LevelDebug, "fetching historical rates", baseField(base), Field{Key: "date", Value: date.String()})
 	var latest *RateResponse
-	if _, err := s.client.Do(request, &latest); err != nil {
+	response, err := s.client.Do(request, &latest)
+	if err != nil {
+		s.client.Cache.fail(base, date, err)
 		return err
 	}
 
+	// The expired rates we hold are still current.
+	if response.StatusCode == http.StatusNotModified {
+		if !validated || !s.client.Cache.revalidate(base, date) {
+			return ErrNothingToRevalidate
+		}
+		return nil
+	}
+
+	latest.Date = date.String()
+	latest.FetchedAt = time.Now().Unix()
 	s.SetBaseCurrency(latest.Base)
 
-	// Store our results.
-	s.client.Cache.Store(latest, date)
+	// Persist and store our results.
+	if err := s.client.persist(latest, date); err != nil {
+		return err
+	}
+	s.client.Cache.storeOn(latest, date, response.Header)
 
 	return nil
 }
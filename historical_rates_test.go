@@ -14,7 +14,7 @@ func TestHistoricalRates_List(t *testing.T) {
 	NewWithT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, defaultCurrency, 1*time.Minute)
+	client := newClient(t, defaultCurrency)
 
 	historicalDate := time.Now().AddDate(0, -2, -5)
 
@@ -39,7 +39,7 @@ func TestHistoricalRate_Get(t *testing.T) {
 	NewWithT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, defaultCurrency, 1*time.Minute)
+	client := newClient(t, defaultCurrency)
 
 	historicalDate := time.Now().AddDate(0, -2, -5)
 
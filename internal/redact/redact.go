@@ -0,0 +1,60 @@
+// Package redact removes secrets, such as OXR app IDs, from what's logged or
+// recorded.
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces app IDs in URLs and errors.
+const Redacted = "REDACTED"
+
+// Headers are the headers that may carry credentials or session state, and
+// are never logged or recorded.
+var Headers = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+}
+
+// URL returns a copy of u with the app ID replaced.
+func URL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	out := *u
+	query := out.Query()
+	if query.Get("app_id") != "" {
+		query.Set("app_id", Redacted)
+		out.RawQuery = query.Encode()
+	}
+	return &out
+}
+
+// Header returns a copy of h without any of Headers, and with the given
+// secrets replaced wherever they appear in the rest.
+func Header(h http.Header, secrets ...string) http.Header {
+	out := h.Clone()
+	for _, name := range Headers {
+		out.Del(name)
+	}
+	for name, values := range out {
+		for i, value := range values {
+			out[name][i] = String(value, secrets...)
+		}
+	}
+	return out
+}
+
+// String returns s with the given secrets replaced wherever they appear.
+func String(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
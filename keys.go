@@ -0,0 +1,507 @@
+package dinero
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoKeys is returned when a KeyPool's source has no app IDs.
+	ErrNoKeys = errors.New("no usable app IDs")
+)
+
+// KeysExhaustedError is returned by a KeyPool when every app ID has been
+// rejected or run out of quota, until the soonest is tried again.
+type KeysExhaustedError struct {
+	RetryAt time.Time
+}
+
+func (e *KeysExhaustedError) Error() string {
+	return fmt.Sprintf("all app IDs rejected or out of quota until %s", e.RetryAt.Format(time.RFC3339))
+}
+
+const (
+	// defaultInvalidCooldown is how long a rejected app ID is set aside
+	// before it's tried again.
+	defaultInvalidCooldown = time.Hour
+	// defaultExhaustedCooldown is how long an app ID out of quota is set
+	// aside, when neither OXR nor usage.json say for how long.
+	defaultExhaustedCooldown = 5 * time.Minute
+)
+
+// KeyProvider supplies the app ID for each request, in place of
+// Client.AppID, and is told how each request went so it can rotate keys.
+type KeyProvider interface {
+	// Key returns the app ID to use for the next request.
+	Key() (string, error)
+	// Report is called with the outcome of a request made with key: nil, or
+	// the error response from OXR.
+	Report(key string, err error)
+}
+
+// KeySource loads the app IDs for a KeyPool.
+type KeySource func() ([]string, error)
+
+// StaticKeys returns a source of the given app IDs.
+func StaticKeys(keys ...string) KeySource {
+	return func() ([]string, error) {
+		return keys, nil
+	}
+}
+
+// FileKeys returns a source of the app IDs in a file, one per line. Blank
+// lines and lines starting with # are ignored.
+func FileKeys(path string) KeySource {
+	return func() ([]string, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys := []string{}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			keys = append(keys, line)
+		}
+		return keys, scanner.Err()
+	}
+}
+
+// EnvKeys returns a source of the app IDs in an environment variable,
+// separated by commas or whitespace.
+func EnvKeys(name string) KeySource {
+	return func() ([]string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		return strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		}), nil
+	}
+}
+
+// KeyUsage is what a KeyPool knows of an app ID's use.
+type KeyUsage struct {
+	// Key is the app ID, redacted to its last four characters.
+	Key string
+	// Requests is the number of requests made with the key by the pool.
+	Requests int64
+	// Quota and Remaining are the key's request quota and the requests
+	// remaining in it, as last reported by usage.json and counted down since.
+	// Both are -1 if unknown, and Quota is -1 for unlimited plans.
+	Quota     int64
+	Remaining int64
+	// Invalid is set once OXR rejects the key.
+	Invalid bool
+	// Exhausted is set once the key runs out of quota.
+	Exhausted bool
+	// RetryAt is when an invalid or exhausted key will be tried again.
+	RetryAt time.Time
+	// LastError is the last error response OXR gave for the key.
+	LastError error
+}
+
+// keyState is a KeyPool's record of an app ID.
+type keyState struct {
+	key       string
+	requests  int64
+	quota     int64
+	remaining int64
+	invalid   bool
+	exhausted bool
+	// retryAt is when the key is tried again, if invalid or exhausted.
+	retryAt time.Time
+	// resets is when the key's billing period ends, if known.
+	resets    time.Time
+	lastError error
+}
+
+// usable reports whether the key can be used for requests now.
+func (k *keyState) usable(now time.Time) bool {
+	return !k.invalid && !k.exhausted || !now.Before(k.retryAt)
+}
+
+// reset clears what's been found wrong with the key.
+func (k *keyState) reset() {
+	k.invalid, k.exhausted, k.retryAt = false, false, time.Time{}
+}
+
+// KeyPool is a KeyProvider that uses several app IDs, one at a time. It
+// moves to the next when OXR rejects the current one (invalid_app_id) or it
+// runs out of quota, going by 429 responses and the usage reported by
+// usage.json. Rejected keys are set aside for an hour, and keys out of quota
+// until their billing period ends (as reported by usage.json), OXR's
+// Retry-After, or five minutes, before they're tried again. With every key
+// set aside, the source is checked once for rotated keys, and failing that
+// requests fail with a KeysExhaustedError until the soonest key is tried
+// again. Keys can be reloaded from their source at any time, keeping the
+// usage of those still present.
+//
+//	pool, err := dinero.NewKeyPool(dinero.FileKeys("/etc/secrets/oxr"))
+//	client.Keys = pool
+//	stop := pool.ReloadEvery(time.Hour)
+type KeyPool struct {
+	source KeySource
+
+	mu                sync.Mutex
+	keys              []*keyState
+	current           int
+	invalidCooldown   time.Duration
+	exhaustedCooldown time.Duration
+	// exhaustedUntil is when the soonest key is tried again, once every key
+	// has been found set aside, so the source isn't read again until then.
+	exhaustedUntil time.Time
+}
+
+// NewKeyPool creates a pool of the app IDs loaded from source.
+func NewKeyPool(source KeySource) (*KeyPool, error) {
+	p := &KeyPool{
+		source:            source,
+		invalidCooldown:   defaultInvalidCooldown,
+		exhaustedCooldown: defaultExhaustedCooldown,
+	}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetCooldown will set how long rejected keys, and keys out of quota (when
+// neither OXR nor usage.json say for how long), are set aside before they're
+// tried again.
+func (p *KeyPool) SetCooldown(invalid, exhausted time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.invalidCooldown = invalid
+	p.exhaustedCooldown = exhausted
+}
+
+// Reload will reload the app IDs from the pool's source, giving every key a
+// fresh start. Keys still present keep their usage, and the current key
+// stays current.
+func (p *KeyPool) Reload() error {
+	keys, err := p.source()
+	if err != nil {
+		return err
+	}
+	return p.reload(keys)
+}
+
+// reload replaces the pool's app IDs with keys.
+func (p *KeyPool) reload(keys []string) error {
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := map[string]*keyState{}
+	for _, state := range p.keys {
+		existing[state.key] = state
+	}
+	current := ""
+	if p.current < len(p.keys) {
+		current = p.keys[p.current].key
+	}
+
+	p.keys = make([]*keyState, 0, len(keys))
+	p.current = 0
+	p.exhaustedUntil = time.Time{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		state, ok := existing[key]
+		if !ok {
+			state = &keyState{key: key, quota: -1, remaining: -1}
+		}
+		state.reset()
+		if key == current {
+			p.current = len(p.keys)
+		}
+		p.keys = append(p.keys, state)
+	}
+	return nil
+}
+
+// ReloadEvery will reload the app IDs from the pool's source every interval,
+// until the returned function is called. Failed reloads keep the keys
+// already loaded.
+func (p *KeyPool) ReloadEvery(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = p.Reload()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Key implements KeyProvider, returning the current app ID, or the next
+// usable one if it isn't. If none are usable, the keys are reloaded from the
+// source if they've been rotated, and otherwise a KeysExhaustedError is
+// returned, without reading the source again, until the soonest key is tried
+// again.
+func (p *KeyPool) Key() (string, error) {
+	if key, ok := p.next(); ok {
+		return key, nil
+	}
+	if err := p.exhausted(); err != nil {
+		return "", err
+	}
+	if keys, err := p.source(); err == nil && p.changed(keys) {
+		if err := p.reload(keys); err == nil {
+			if key, ok := p.next(); ok {
+				return key, nil
+			}
+		}
+	}
+	return "", p.exhaustAll()
+}
+
+// next returns the current app ID, moving on from unusable ones.
+func (p *KeyPool) next() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(p.keys); i++ {
+		index := (p.current + i) % len(p.keys)
+		if state := p.keys[index]; state.usable(now) {
+			p.current = index
+			return state.key, true
+		}
+	}
+	return "", false
+}
+
+// exhausted returns a KeysExhaustedError if every key has already been found
+// set aside, and none is due to be tried again yet.
+func (p *KeyPool) exhausted() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Now().Before(p.exhaustedUntil) {
+		return &KeysExhaustedError{RetryAt: p.exhaustedUntil}
+	}
+	return nil
+}
+
+// exhaustAll records that every key is set aside, until the soonest is tried
+// again, and returns the KeysExhaustedError saying so.
+func (p *KeyPool) exhaustAll() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return ErrNoKeys
+	}
+	retryAt := p.keys[0].retryAt
+	for _, state := range p.keys[1:] {
+		if state.retryAt.Before(retryAt) {
+			retryAt = state.retryAt
+		}
+	}
+	p.exhaustedUntil = retryAt
+	return &KeysExhaustedError{RetryAt: retryAt}
+}
+
+// changed reports whether keys differ from the pool's app IDs.
+func (p *KeyPool) changed(keys []string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if p.find(key) == nil {
+			return true
+		}
+		seen[key] = true
+	}
+	return len(seen) != len(p.keys)
+}
+
+// Report implements KeyProvider.
+func (p *KeyPool) Report(key string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.find(key)
+	if state == nil {
+		return
+	}
+	state.requests++
+	now := time.Now()
+	if err == nil {
+		state.reset()
+		p.exhaustedUntil = time.Time{}
+		if state.remaining > 0 {
+			state.remaining--
+			if state.remaining == 0 {
+				p.exhaust(state, now, 0)
+			}
+		}
+		return
+	}
+
+	state.lastError = err
+	switch {
+	case isInvalidKey(err):
+		state.invalid = true
+		state.retryAt = now.Add(p.invalidCooldown)
+	case isQuotaExhausted(err):
+		var rsp *ErrorResponse
+		errors.As(err, &rsp)
+		p.exhaust(state, now, rsp.RetryAfter())
+	}
+}
+
+// exhaust sets the key aside as out of quota, for retryAfter if given, or
+// else until its billing period ends, if known. The caller must hold p.mu.
+func (p *KeyPool) exhaust(state *keyState, now time.Time, retryAfter time.Duration) {
+	state.exhausted = true
+	switch {
+	case retryAfter > 0:
+		state.retryAt = now.Add(retryAfter)
+	case state.resets.After(now):
+		state.retryAt = state.resets
+	default:
+		state.retryAt = now.Add(p.exhaustedCooldown)
+	}
+}
+
+// ReportUsage records the usage reported by usage.json for key.
+func (p *KeyPool) ReportUsage(key string, usage Usage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.find(key)
+	if state == nil {
+		return
+	}
+	now := time.Now()
+	state.quota = usage.RequestsQuota
+	state.remaining = usage.RequestsRemaining
+	state.resets = usage.resets(now)
+	if usage.Unlimited() {
+		state.remaining = -1
+	}
+	// A new billing period brings more quota.
+	if !usage.Unlimited() && usage.RequestsRemaining <= 0 {
+		p.exhaust(state, now, 0)
+	} else if state.exhausted {
+		state.reset()
+		p.exhaustedUntil = time.Time{}
+	}
+}
+
+// Usage returns what the pool knows of each app ID's use, in order.
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := make([]KeyUsage, 0, len(p.keys))
+	for _, state := range p.keys {
+		usage = append(usage, KeyUsage{
+			Key:       maskKey(state.key),
+			Requests:  state.requests,
+			Quota:     state.quota,
+			Remaining: state.remaining,
+			Invalid:   state.invalid,
+			Exhausted: state.exhausted,
+			RetryAt:   state.retryAt,
+			LastError: state.lastError,
+		})
+	}
+	return usage
+}
+
+// inUse returns the current app ID, without moving on from it.
+func (p *KeyPool) inUse() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.current >= len(p.keys) {
+		return ""
+	}
+	return p.keys[p.current].key
+}
+
+// secrets returns the pool's app IDs, for redaction.
+func (p *KeyPool) secrets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := make([]string, 0, len(p.keys))
+	for _, state := range p.keys {
+		keys = append(keys, state.key)
+	}
+	return keys
+}
+
+// find returns the state of key. The caller must hold p.mu.
+func (p *KeyPool) find(key string) *keyState {
+	for _, state := range p.keys {
+		if state.key == key {
+			return state
+		}
+	}
+	return nil
+}
+
+// isInvalidKey reports whether err is OXR rejecting the app ID.
+func isInvalidKey(err error) bool {
+	var rsp *ErrorResponse
+	if !errors.As(err, &rsp) {
+		return false
+	}
+	switch rsp.Message {
+	case "invalid_app_id", "missing_app_id":
+		return true
+	}
+	return rsp.Response != nil && rsp.Response.StatusCode == http.StatusUnauthorized
+}
+
+// isQuotaExhausted reports whether err is OXR restricting the app ID for
+// over-use or running out of quota.
+func isQuotaExhausted(err error) bool {
+	var rsp *ErrorResponse
+	if !errors.As(err, &rsp) {
+		return false
+	}
+	return rsp.Message == "access_restricted" ||
+		rsp.Response != nil && rsp.Response.StatusCode == http.StatusTooManyRequests
+}
+
+// maskKey returns key with all but its last four characters hidden.
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
@@ -0,0 +1,203 @@
+package dinero
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// TestKeyPool will test rotating app IDs when they're rejected or run out of quota.
+func TestKeyPool(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetAppIDs("spent-key", "good-key")
+
+	pool, err := NewKeyPool(StaticKeys("revoked-key", "spent-key", "good-key"))
+	Expect(err).Should(BeNil())
+
+	client := NewClient("", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Keys = pool
+
+	// The spent key's quota is used up.
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("app_id") == "spent-key" && endpointOf(req.URL.Path) != usageEndpoint {
+				rsp := httptestResponse(req, http.StatusTooManyRequests, `{"error":true,"status":429,"message":"access_restricted","description":"Quota reached."}`)
+				return rsp, nil
+			}
+			return next.RoundTrip(req)
+		})
+	})
+
+	// The request is retried until a key works.
+	_, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+	requests := server.Requests()
+	Expect(requests).Should(HaveLen(2))
+	Expect(requests[0].Query.Get("app_id")).Should(Equal("revoked-key"))
+	Expect(requests[1].Query.Get("app_id")).Should(Equal("good-key"))
+
+	usage := pool.Usage()
+	Expect(usage).Should(HaveLen(3))
+	Expect(usage[0].Key).Should(Equal("*******-key"))
+	Expect(usage[0].Invalid).Should(BeTrue())
+	Expect(usage[1].Exhausted).Should(BeTrue())
+	Expect(usage[2].Requests).Should(Equal(int64(1)))
+
+	// The good key stays current.
+	key, err := pool.Key()
+	Expect(err).Should(BeNil())
+	Expect(key).Should(Equal("good-key"))
+
+	// Quotas are tracked from usage.json, and counted down.
+	server.SetQuota(2)
+	Expect(client.Usage.RefreshKeys()).Should(BeNil())
+	usage = pool.Usage()
+	Expect(usage[2].Quota).Should(Equal(int64(2)))
+	Expect(usage[2].Remaining).Should(Equal(int64(1)))
+	Expect(usage[1].Exhausted).Should(BeFalse())
+
+	client.Cache.Expire("USD", time.Now())
+	_, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(pool.Usage()[2].Exhausted).Should(BeTrue())
+
+	// The spent key is tried again, as usage.json reported quota for it, but
+	// is still restricted.
+	client.Cache.Expire("USD", time.Now())
+	_, err = client.Rates.List()
+	Expect(isQuotaExhausted(err)).Should(BeTrue())
+	// Both are out of quota until the billing period ends.
+	usage = pool.Usage()
+	Expect(usage[1].RetryAt).Should(BeTemporally(">", time.Now()))
+	Expect(usage[2].RetryAt).Should(Equal(usage[1].RetryAt))
+
+	// With every key set aside, requests fail without calling OXR until the
+	// soonest is tried again: the revoked key, an hour on.
+	before := len(server.Requests())
+	client.Cache.Expire("USD", time.Now())
+	_, err = client.Rates.List()
+	var exhausted *KeysExhaustedError
+	Expect(errors.As(err, &exhausted)).Should(BeTrue())
+	Expect(exhausted.RetryAt).Should(Equal(usage[0].RetryAt))
+	Expect(server.Requests()).Should(HaveLen(before))
+}
+
+// TestKeyPool_Cooldown will test keys set aside being tried again.
+func TestKeyPool_Cooldown(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	reads := 0
+	pool, err := NewKeyPool(func() ([]string, error) {
+		reads++
+		return []string{"only-key"}, nil
+	})
+	Expect(err).Should(BeNil())
+	pool.SetCooldown(time.Hour, 20*time.Millisecond)
+
+	// A single 429 doesn't take the only key out for good.
+	rateLimited := &ErrorResponse{Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}, Message: "too_many_requests"}
+	pool.Report("only-key", rateLimited)
+	Expect(pool.Usage()[0].Exhausted).Should(BeTrue())
+	_, err = pool.Key()
+	var exhausted *KeysExhaustedError
+	Expect(errors.As(err, &exhausted)).Should(BeTrue())
+	Expect(exhausted.RetryAt).Should(Equal(pool.Usage()[0].RetryAt))
+
+	// The source is checked for rotated keys once, not on every call.
+	_, err = pool.Key()
+	Expect(errors.As(err, &exhausted)).Should(BeTrue())
+	Expect(reads).Should(Equal(2))
+
+	// Once the cooldown is over, a success clears it.
+	time.Sleep(30 * time.Millisecond)
+	key, err := pool.Key()
+	Expect(err).Should(BeNil())
+	Expect(key).Should(Equal("only-key"))
+	pool.Report("only-key", nil)
+	Expect(pool.Usage()[0].Exhausted).Should(BeFalse())
+
+	// Retry-After is honoured.
+	rateLimited.Response.Header.Set("Retry-After", "60")
+	pool.Report("only-key", rateLimited)
+	Expect(pool.Usage()[0].RetryAt).Should(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+
+	// Reloading gives every key a fresh start.
+	pool.Report("only-key", &ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}, Message: "invalid_app_id"})
+	Expect(pool.Usage()[0].Invalid).Should(BeTrue())
+	Expect(pool.Reload()).Should(BeNil())
+	usage := pool.Usage()[0]
+	Expect(usage.Invalid).Should(BeFalse())
+	Expect(usage.Exhausted).Should(BeFalse())
+	Expect(usage.Requests).Should(Equal(int64(4)))
+}
+
+// TestKeyPool_Reload will test reloading app IDs from a file or the environment.
+func TestKeyPool_Reload(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "keys")
+	Expect(ioutil.WriteFile(path, []byte("# OXR app IDs\nfirst-key\n\nsecond-key\n"), 0600)).Should(BeNil())
+
+	pool, err := NewKeyPool(FileKeys(path))
+	Expect(err).Should(BeNil())
+	pool.Report("first-key", &ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}, Message: "invalid_app_id"})
+	key, err := pool.Key()
+	Expect(err).Should(BeNil())
+	Expect(key).Should(Equal("second-key"))
+
+	// Rotated keys are picked up without rebuilding anything, keeping what's
+	// known of the others.
+	Expect(ioutil.WriteFile(path, []byte("second-key\nthird-key\n"), 0600)).Should(BeNil())
+	Expect(pool.Reload()).Should(BeNil())
+	Expect(pool.Usage()).Should(HaveLen(2))
+	key, err = pool.Key()
+	Expect(err).Should(BeNil())
+	Expect(key).Should(Equal("second-key"))
+
+	// A pool with no usable keys picks up rotated ones.
+	pool.Report("second-key", &ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}, Message: "invalid_app_id"})
+	pool.Report("third-key", &ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}, Message: "invalid_app_id"})
+	Expect(ioutil.WriteFile(path, []byte("fourth-key\n"), 0600)).Should(BeNil())
+	key, err = pool.Key()
+	Expect(err).Should(BeNil())
+	Expect(key).Should(Equal("fourth-key"))
+
+	// A source with no keys can't be loaded.
+	Expect(ioutil.WriteFile(path, []byte("# None yet\n"), 0600)).Should(BeNil())
+	Expect(errors.Is(pool.Reload(), ErrNoKeys)).Should(BeTrue())
+
+	// Keys can come from the environment too.
+	os.Setenv("DINERO_TEST_KEYS", "env-key-1, env-key-2")
+	defer os.Unsetenv("DINERO_TEST_KEYS")
+	keys, err := EnvKeys("DINERO_TEST_KEYS")()
+	Expect(err).Should(BeNil())
+	Expect(keys).Should(Equal([]string{"env-key-1", "env-key-2"}))
+	_, err = EnvKeys("DINERO_TEST_MISSING")()
+	Expect(err).ShouldNot(BeNil())
+}
+
+// httptestResponse builds a JSON response to req.
+func httptestResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
@@ -0,0 +1,39 @@
+package dinero
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// TestRateLimiter will test requests being spaced out beyond the burst.
+func TestRateLimiter(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Limiter = NewRateLimiter(20, time.Second, 2)
+
+	// The burst goes straight through, the rest are spaced 50ms apart.
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := client.HistoricalRates.ListOn(Date{Year: 2021, Month: time.January, Day: i + 1})
+		Expect(err).Should(BeNil())
+	}
+	Expect(time.Since(start)).Should(BeNumerically(">=", 90*time.Millisecond))
+
+	// Waiting stops when the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	limiter := NewRateLimiter(1, time.Hour, 1)
+	Expect(limiter.Wait(ctx)).Should(BeNil())
+	Expect(limiter.Wait(ctx)).Should(Equal(context.DeadlineExceeded))
+}
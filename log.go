@@ -0,0 +1,116 @@
+package dinero
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mattevans/dinero/internal/redact"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	// LevelDebug is for requests, responses and cache events.
+	LevelDebug Level = iota
+	// LevelInfo is for rates being refreshed from OXR.
+	LevelInfo
+	// LevelWarn is for problems the client recovered from, or that the
+	// caller can fix, e.g. error responses from OXR and warnings.
+	LevelWarn
+	// LevelError is for requests that failed to reach OXR.
+	LevelError
+)
+
+// String returns the level's name.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Field is a key-value pair attached to a log entry. Values are strings,
+// bools, ints, durations, times or errors.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives the client's log entries. It's small enough to adapt any
+// structured logger to, e.g. log/slog or zap. The app ID is redacted from
+// every URL and error logged.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// RedactURL returns a copy of u with the app ID replaced, safe for logging.
+func RedactURL(u *url.URL) *url.URL {
+	return redact.URL(u)
+}
+
+// redactError returns err with the app ID removed from its URL, if it's a
+// *url.Error, as the HTTP client's errors include the request URL.
+func redactError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	u, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil {
+		return &url.Error{Op: urlErr.Op, URL: "", Err: urlErr.Err}
+	}
+	return &url.Error{Op: urlErr.Op, URL: RedactURL(u).String(), Err: urlErr.Err}
+}
+
+// log sends an entry to the client's Logger, if it has one, with the app ID
+// redacted from any field, and credentials and cookies left out of headers.
+func (c *Client) log(level Level, msg string, fields ...Field) {
+	if c.Logger == nil {
+		return
+	}
+	for i, field := range fields {
+		switch v := field.Value.(type) {
+		case *url.URL:
+			fields[i].Value = RedactURL(v).String()
+		case error:
+			fields[i].Value = c.redact(redactError(v).Error())
+		case string:
+			fields[i].Value = c.redact(v)
+		case http.Header:
+			fields[i].Value = redact.Header(v, c.secrets()...)
+		}
+	}
+	c.Logger.Log(level, msg, fields...)
+}
+
+// redact replaces the client's app IDs wherever they appear in s.
+func (c *Client) redact(s string) string {
+	return redact.String(s, c.secrets()...)
+}
+
+// secrets returns the client's app IDs.
+func (c *Client) secrets() []string {
+	secrets := []string{c.AppID}
+	if pool, ok := c.Keys.(*KeyPool); ok {
+		secrets = append(secrets, pool.secrets()...)
+	}
+	return secrets
+}
+
+// baseField returns the field for a base currency, which OXR takes to be USD
+// if none is passed.
+func baseField(base string) Field {
+	if base == "" {
+		base = defaultBaseCurrency
+	}
+	return Field{Key: "base", Value: base}
+}
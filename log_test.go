@@ -0,0 +1,99 @@
+package dinero
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// logEntry is an entry recorded by testLogger.
+type logEntry struct {
+	level  Level
+	msg    string
+	fields map[string]interface{}
+}
+
+// testLogger records the entries it's sent.
+type testLogger struct {
+	entries []logEntry
+}
+
+func (l *testLogger) Log(level Level, msg string, fields ...Field) {
+	entry := logEntry{level: level, msg: msg, fields: map[string]interface{}{}}
+	for _, field := range fields {
+		entry.fields[field.Key] = field.Value
+	}
+	l.entries = append(l.entries, entry)
+}
+
+// find returns the first entry with the given message.
+func (l *testLogger) find(msg string) *logEntry {
+	for i := range l.entries {
+		if l.entries[i].msg == msg {
+			return &l.entries[i]
+		}
+	}
+	return nil
+}
+
+// TestLogger will test logging requests, responses, cache events and refreshes with the app ID redacted.
+func TestLogger(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	logger := &testLogger{}
+	client := NewClient("secret-app-id", "", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Logger = logger
+
+	_, err := client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+
+	request := logger.find("request")
+	Expect(request).ShouldNot(BeNil())
+	Expect(request.level).Should(Equal(LevelDebug))
+	Expect(request.fields["url"]).Should(Equal(server.URL + "/api/latest.json?app_id=REDACTED"))
+	Expect(logger.find("response").fields["status"]).Should(Equal(200))
+	Expect(logger.find("refreshing latest rates").level).Should(Equal(LevelInfo))
+	Expect(logger.find("refreshed latest rates").fields["base"]).Should(Equal("USD"))
+	Expect(logger.find("cache lookup").fields).Should(HaveKeyWithValue("hit", false))
+
+	// Error responses are logged, and never expose the app ID.
+	server.Fail(dinerotest.Historical, dinerotest.ErrNotAvailable)
+	_, err = client.HistoricalRates.ListOn(NewDate(2021, 1, 1))
+	var errRsp *ErrorResponse
+	Expect(errors.As(err, &errRsp)).Should(BeTrue())
+	Expect(errRsp.Request.URL.Query().Get("app_id")).Should(Equal("REDACTED"))
+	Expect(fmt.Sprintf("%+v", errRsp)).ShouldNot(ContainSubstring("secret-app-id"))
+	Expect(logger.find("error response").fields["code"]).Should(Equal("not_available"))
+
+	// As are failed requests.
+	server.Close()
+	client.Cache.Expire("USD", time.Now())
+	_, err = client.Rates.List()
+	Expect(err).ShouldNot(BeNil())
+	Expect(err.Error()).ShouldNot(ContainSubstring("secret-app-id"))
+	failed := logger.find("request failed")
+	Expect(failed.level).Should(Equal(LevelError))
+	Expect(failed.fields["error"]).Should(ContainSubstring("app_id=REDACTED"))
+
+	for _, entry := range logger.entries {
+		for _, value := range entry.fields {
+			Expect(strings.Contains(fmt.Sprint(value), "secret-app-id")).Should(BeFalse())
+		}
+	}
+
+	// Warnings are logged too.
+	client.warn(errors.New("careful with secret-app-id"))
+	Expect(logger.entries[len(logger.entries)-1].level).Should(Equal(LevelWarn))
+	Expect(logger.entries[len(logger.entries)-1].fields["error"]).Should(Equal("careful with REDACTED"))
+}
@@ -0,0 +1,166 @@
+package dinero
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// LookupMode controls which day's rates historical lookups fall back to when
+// there are none for the requested day.
+type LookupMode int
+
+const (
+	// LookupExact only ever uses the requested day.
+	LookupExact LookupMode = iota
+	// LookupPrevious falls back to the last available day before the
+	// requested one.
+	LookupPrevious
+	// LookupNext falls back to the first available day after the requested
+	// one.
+	LookupNext
+	// LookupNearest falls back to the closest available day either side of
+	// the requested one, preferring the earlier day on a tie.
+	LookupNearest
+
+	// defaultMaxDistance is the number of days looked either side of the
+	// requested day when no maximum distance is set.
+	defaultMaxDistance = 7
+)
+
+// HistoricalRate is a single historical rate, along with the day it was
+// actually taken from.
+type HistoricalRate struct {
+	Code string
+	Base string
+	Rate float64
+	// Requested is the date that was asked for.
+	Requested Date
+	// Date is the date whose rates were used.
+	Date Date
+}
+
+// SetLookupMode will set how historical lookups fall back when there are no
+// rates for the requested day, looking at most maxDistance days away from it.
+// A maxDistance of zero or less means a week.
+func (s *HistoricalRatesService) SetLookupMode(mode LookupMode, maxDistance int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxDistance <= 0 {
+		maxDistance = defaultMaxDistance
+	}
+	s.lookupMode = mode
+	s.maxDistance = maxDistance
+}
+
+// Lookup will fetch a single rate for a given currency, falling back to
+// another day according to the lookup mode, and report the day used. The
+// requested day is the one the given time falls on in the client's Location.
+func (s *HistoricalRatesService) Lookup(code string, date time.Time) (*HistoricalRate, error) {
+	return s.LookupOn(code, s.client.dateOf(date))
+}
+
+// LookupOn is like Lookup, but for a calendar date.
+func (s *HistoricalRatesService) LookupOn(code string, date Date) (*HistoricalRate, error) {
+	return s.lookupOn(context.Background(), code, date)
+}
+
+func (s *HistoricalRatesService) lookupOn(ctx context.Context, code string, date Date) (*HistoricalRate, error) {
+	// No code passed, let them know!
+	if code == "" {
+		return nil, errors.New("currency code must be passed")
+	}
+
+	var single *float64
+	rsp, used, err := s.resolve(ctx, date, func(rsp *RateResponse, day Date) error {
+		var err error
+		single, err = s.client.lookup(rsp, code, day)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &HistoricalRate{
+		Code:      code,
+		Base:      rsp.Base,
+		Rate:      *single,
+		Requested: date,
+		Date:      used,
+	}, nil
+}
+
+// resolve returns the rates for the first candidate day (per the lookup mode)
+// that has rates available and passes check, along with that day.
+func (s *HistoricalRatesService) resolve(ctx context.Context, date Date, check func(rsp *RateResponse, day Date) error) (*RateResponse, Date, error) {
+	var lastErr error
+	for _, day := range s.candidates(date) {
+		rsp, err := s.list(ctx, day)
+		if err == nil && check != nil {
+			err = check(rsp, day)
+		}
+		if err == nil {
+			return rsp, day, nil
+		}
+		if !isUnavailable(err) {
+			return nil, Date{}, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = ErrRatesNotFound
+	}
+	return nil, Date{}, lastErr
+}
+
+// candidates returns the days to try for the requested date, in order of
+// preference. Fallback days in the future are never tried.
+func (s *HistoricalRatesService) candidates(date Date) []Date {
+	s.mu.RLock()
+	mode, maxDistance := s.lookupMode, s.maxDistance
+	s.mu.RUnlock()
+
+	days := []Date{date}
+	if mode == LookupExact {
+		return days
+	}
+
+	today := s.client.today()
+	add := func(day Date) {
+		if !day.After(today) {
+			days = append(days, day)
+		}
+	}
+
+	// The requested day itself is only worth trying if it's not in the future.
+	if date.After(today) {
+		days = days[:0]
+	}
+	for distance := 1; distance <= maxDistance; distance++ {
+		if mode == LookupPrevious || mode == LookupNearest {
+			add(date.AddDays(-distance))
+		}
+		if mode == LookupNext || mode == LookupNearest {
+			add(date.AddDays(distance))
+		}
+	}
+	return days
+}
+
+// isUnavailable reports whether err means there are no rates for a day (or
+// code), rather than the lookup failing.
+func isUnavailable(err error) bool {
+	if errors.Is(err, ErrRatesNotFound) || errors.Is(err, ErrNotStored) {
+		return true
+	}
+
+	var rsp *ErrorResponse
+	if errors.As(err, &rsp) {
+		switch rsp.Message {
+		case "not_available", "invalid_date":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package dinero
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// weekdayHandler serves historical rates for weekdays only, with NZD data starting on 2021-01-05.
+func weekdayHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		day := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/historical/"), ".json")
+		date, _ := time.Parse("2006-01-02", day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": true, "status": 400, "message": "not_available", "description": "Historical rates for the requested date are not available"}`))
+			return
+		}
+
+		rates := map[string]float64{"AUD": float64(date.Day())}
+		if day >= "2021-01-05" {
+			rates["NZD"] = 1.5
+		}
+		_ = json.NewEncoder(w).Encode(&RateResponse{Base: "USD", Rates: rates})
+	})
+}
+
+// TestLookup_Modes will test falling back to other days when the requested day has no rates.
+func TestLookup_Modes(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	saturday := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+	friday := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", weekdayHandler())
+
+	// Exact lookups fail outright.
+	_, err := client.HistoricalRates.Get("AUD", saturday)
+	var rsp *ErrorResponse
+	Expect(errors.As(err, &rsp)).Should(BeTrue())
+	Expect(rsp.Message).Should(Equal("not_available"))
+
+	client.HistoricalRates.SetLookupMode(LookupPrevious, 3)
+	rate, err := client.HistoricalRates.Lookup("AUD", saturday)
+	Expect(err).Should(BeNil())
+	Expect(rate.Rate).Should(Equal(1.0))
+	Expect(rate.Date).Should(Equal(DateOf(friday)))
+	Expect(rate.Requested).Should(Equal(DateOf(saturday)))
+
+	list, err := client.HistoricalRates.List(saturday)
+	Expect(err).Should(BeNil())
+	Expect(list.Date).Should(Equal("2021-01-01"))
+
+	client.HistoricalRates.SetLookupMode(LookupNext, 3)
+	rate, err = client.HistoricalRates.Lookup("AUD", saturday)
+	Expect(err).Should(BeNil())
+	Expect(rate.Date).Should(Equal(DateOf(monday)))
+
+	// Sunday is nearer Monday than Friday.
+	client.HistoricalRates.SetLookupMode(LookupNearest, 3)
+	rate, err = client.HistoricalRates.Lookup("AUD", saturday.AddDate(0, 0, 1))
+	Expect(err).Should(BeNil())
+	Expect(rate.Date).Should(Equal(DateOf(monday)))
+
+	// Codes whose data hasn't started yet fall back too.
+	client.HistoricalRates.SetLookupMode(LookupNext, 7)
+	rate, err = client.HistoricalRates.Lookup("NZD", friday)
+	Expect(err).Should(BeNil())
+	Expect(rate.Date).Should(Equal(DateOf(monday).AddDays(1)))
+
+	// But not further than the maximum distance.
+	client.HistoricalRates.SetLookupMode(LookupPrevious, 1)
+	_, err = client.HistoricalRates.Get("AUD", saturday.AddDate(0, 0, 1))
+	Expect(errors.As(err, &rsp)).Should(BeTrue())
+}
+
+// TestLookup_Future will test future dates fall back to the latest available day without asking OXR.
+func TestLookup_Future(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	var requested []string
+	client := newTestClient(t, "USD", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(&RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.3}})
+	}))
+	client.HistoricalRates.SetLookupMode(LookupPrevious, 3)
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	rate, err := client.HistoricalRates.Lookup("AUD", tomorrow)
+	Expect(err).Should(BeNil())
+	Expect(rate.Date).Should(Equal(DateOf(time.Now().UTC())))
+	Expect(requested).Should(HaveLen(1))
+}
@@ -0,0 +1,93 @@
+package dinero
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Metrics is told about a client's requests to OXR and their latency, cache
+// hits and misses, errors returned by OXR, the request quota remaining and
+// when the latest rates held were published. It's small enough to adapt any
+// metrics library to; the dineroprom module implements it as a Prometheus
+// collector. Methods may be called concurrently.
+type Metrics interface {
+	// ObserveRequest is called after each request to OXR, with the endpoint
+	// (e.g. latest or historical), the response's status code, or zero if no
+	// response was received, and how long it took.
+	ObserveRequest(endpoint string, status int, took time.Duration)
+	// ObserveError is called with the code of each error response from OXR,
+	// e.g. invalid_app_id.
+	ObserveError(code string)
+	// ObserveCache is called after each cache lookup, with the type of rates
+	// looked up (CacheLatest or CacheHistorical).
+	ObserveCache(kind string, hit bool)
+	// ObserveUsage is called with the usage reported by usage.json.
+	ObserveUsage(usage Usage)
+	// ObserveLatest is called when the latest rates for a base are cached,
+	// with when OXR published them.
+	ObserveLatest(base string, published time.Time)
+}
+
+// The types of rates held, as reported to Metrics.
+const (
+	CacheLatest     = "latest"
+	CacheHistorical = "historical"
+)
+
+// observeRequest reports a request and how long it took to the client's
+// Metrics, if it has any.
+func (c *Client) observeRequest(req *http.Request, status int, took time.Duration) {
+	if c.Metrics == nil {
+		return
+	}
+	c.Metrics.ObserveRequest(endpointOf(req.URL.Path), status, took)
+}
+
+// observeError reports an error response from OXR to the client's Metrics,
+// if it has any.
+func (c *Client) observeError(rsp *ErrorResponse) {
+	if c.Metrics == nil {
+		return
+	}
+	code := rsp.Message
+	if code == "" {
+		code = "unknown"
+	}
+	c.Metrics.ObserveError(code)
+}
+
+// observeCache reports a cache lookup to the client's Metrics, if it has any.
+func (c *Client) observeCache(kind string, hit bool) {
+	if c.Metrics == nil {
+		return
+	}
+	c.Metrics.ObserveCache(kind, hit)
+}
+
+// observeUsage reports usage to the client's Metrics, if it has any.
+func (c *Client) observeUsage(usage Usage) {
+	if c.Metrics == nil {
+		return
+	}
+	c.Metrics.ObserveUsage(usage)
+}
+
+// observeLatest reports when the latest rates held for a base were published
+// to the client's Metrics, if it has any.
+func (c *Client) observeLatest(rsp *RateResponse) {
+	if c.Metrics == nil || rsp.Timestamp == 0 {
+		return
+	}
+	c.Metrics.ObserveLatest(rsp.Base, rsp.PublishedAt())
+}
+
+// endpointOf returns the OXR endpoint a request path is for, e.g. latest or
+// historical.
+func endpointOf(path string) string {
+	path = strings.TrimPrefix(path, "/api/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimSuffix(path, ".json")
+}
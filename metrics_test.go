@@ -0,0 +1,110 @@
+package dinero
+
+import (
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// recordingMetrics records what a client tells its Metrics.
+type recordingMetrics struct {
+	mu        sync.Mutex
+	requests  map[string]int
+	errors    map[string]int
+	hits      map[string]int
+	misses    map[string]int
+	remaining int64
+	published map[string]time.Time
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		requests:  map[string]int{},
+		errors:    map[string]int{},
+		hits:      map[string]int{},
+		misses:    map[string]int{},
+		published: map[string]time.Time{},
+	}
+}
+
+func (m *recordingMetrics) ObserveRequest(endpoint string, status int, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[endpoint+" "+strconv.Itoa(status)]++
+}
+
+func (m *recordingMetrics) ObserveError(code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[code]++
+}
+
+func (m *recordingMetrics) ObserveCache(kind string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.hits[kind]++
+	} else {
+		m.misses[kind]++
+	}
+}
+
+func (m *recordingMetrics) ObserveUsage(usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remaining = usage.RequestsRemaining
+}
+
+func (m *recordingMetrics) ObserveLatest(base string, published time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published[base] = published
+}
+
+// TestMetrics will test the client reporting requests, the cache, errors, quota and rate age.
+func TestMetrics(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	published := time.Now().Add(-time.Hour).Truncate(time.Second)
+	server.SetLatest(map[string]float64{"AUD": 1.25}, published)
+	server.SetQuota(1000)
+
+	metrics := newRecordingMetrics()
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Metrics = metrics
+
+	_, err := client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	_, err = client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	_, err = client.HistoricalRates.GetOn("AUD", NewDate(2021, 1, 1))
+	Expect(err).Should(BeNil())
+	_, err = client.Usage.Get()
+	Expect(err).Should(BeNil())
+
+	server.Fail(dinerotest.Historical, dinerotest.ErrNotAvailable)
+	_, err = client.HistoricalRates.ListOn(NewDate(2020, 1, 1))
+	Expect(err).ShouldNot(BeNil())
+
+	Expect(metrics.requests).Should(Equal(map[string]int{
+		"latest 200":     1,
+		"historical 200": 1,
+		"historical 400": 1,
+		"usage 200":      1,
+	}))
+	Expect(metrics.hits).Should(Equal(map[string]int{CacheLatest: 2, CacheHistorical: 1}))
+	Expect(metrics.misses).Should(Equal(map[string]int{CacheLatest: 1, CacheHistorical: 2}))
+	Expect(metrics.errors).Should(Equal(map[string]int{"not_available": 1}))
+	Expect(metrics.remaining).Should(Equal(int64(998)))
+	Expect(metrics.published).Should(HaveLen(1))
+	Expect(metrics.published["USD"].Equal(published)).Should(BeTrue())
+}
@@ -0,0 +1,58 @@
+package dinero
+
+import "net/http"
+
+// Middleware wraps the transport requests to OXR are sent with, to
+// customise them: adding headers, rewriting URLs for a proxy, signing
+// requests for a gateway or capturing responses. Like any RoundTripper, the
+// returned one must not modify the request it's given; clone it first.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper, for writing
+// Middleware.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use will add middleware wrapping every request the client sends, after any
+// already added. The first added is outermost, seeing requests first and
+// responses last. It wraps the transport of the HTTP client set with
+// SetHTTPClient, and shouldn't be called while requests are being made. The
+// middleware is applied once, here and in SetHTTPClient, so any state it sets
+// up is shared by every request.
+func (c *Client) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+	c.wrap()
+}
+
+// wrap builds the HTTP client to send requests with, a copy of the one set
+// with its transport wrapped in the client's middleware.
+func (c *Client) wrap() {
+	if len(c.middleware) == 0 {
+		c.wrapped = nil
+		return
+	}
+
+	transport := c.client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		transport = c.middleware[i](transport)
+	}
+
+	client := *c.client
+	client.Transport = transport
+	c.wrapped = &client
+}
+
+// httpClient returns the HTTP client to send requests with.
+func (c *Client) httpClient() *http.Client {
+	if c.wrapped != nil {
+		return c.wrapped
+	}
+	return c.client
+}
@@ -0,0 +1,105 @@
+package dinero
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// TestClient_Use will test wrapping requests in middleware.
+func TestClient_Use(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	// Requests go to a gateway, which the middleware routes to OXR.
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse("http://gateway.invalid/oxr")
+
+	order := []string{}
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	var captured []byte
+	client.Use(
+		trace("outer"),
+		// Sign requests for the gateway.
+		func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				req.Header.Set("X-Gateway-Token", "signed")
+				return next.RoundTrip(req)
+			})
+		},
+		// Rewrite the gateway's URLs.
+		func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				req.URL.Scheme = target.Scheme
+				req.URL.Host = target.Host
+				req.URL.Path = strings.TrimPrefix(req.URL.Path, "/oxr")
+				req.Host = target.Host
+				return next.RoundTrip(req)
+			})
+		},
+		// Capture raw responses.
+		func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				rsp, err := next.RoundTrip(req)
+				if err != nil {
+					return nil, err
+				}
+				captured, err = ioutil.ReadAll(rsp.Body)
+				rsp.Body.Close()
+				rsp.Body = ioutil.NopCloser(bytes.NewReader(captured))
+				return rsp, err
+			})
+		},
+	)
+	client.Use(trace("inner"))
+
+	rate, err := client.Rates.Get("GBP")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically(">", 0))
+	Expect(order).Should(Equal([]string{"outer", "inner"}))
+	Expect(string(captured)).Should(ContainSubstring(`"GBP"`))
+
+	requests := server.Requests()
+	Expect(requests).Should(HaveLen(1))
+	Expect(requests[0].Path).Should(Equal("/api/latest.json"))
+	Expect(requests[0].Header.Get("X-Gateway-Token")).Should(Equal("signed"))
+	Expect(requests[0].Header.Get("User-Agent")).Should(Equal(userAgent))
+
+	// Middleware is applied once, not per request, so state it sets up is
+	// shared, and reapplied when the HTTP client changes.
+	built := 0
+	client = NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL = target
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		built++
+		return next
+	})
+	for day := 1; day <= 3; day++ {
+		_, err = client.HistoricalRates.ListOn(NewDate(2021, time.January, day))
+		Expect(err).Should(BeNil())
+	}
+	Expect(built).Should(Equal(1))
+	client.SetHTTPClient(&http.Client{Timeout: time.Second})
+	Expect(built).Should(Equal(2))
+}
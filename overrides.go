@@ -0,0 +1,250 @@
+package dinero
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Override is a manually set rate that takes precedence over the rate fetched
+// from OXR for a given base/code pair.
+type Override struct {
+	Base string  `json:"base"`
+	Code string  `json:"code"`
+	Rate float64 `json:"rate"`
+	// From and To bound (inclusively) the dates the override is effective
+	// for. A zero value leaves that end of the range open.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Expires is the point in time after which the override is ignored. A
+	// zero value means the override never expires.
+	Expires time.Time `json:"expires"`
+}
+
+// UnmarshalJSON allows override dates to be given as either plain dates
+// (2006-01-02) or RFC3339 timestamps.
+func (o *Override) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Base    string  `json:"base"`
+		Code    string  `json:"code"`
+		Rate    float64 `json:"rate"`
+		From    string  `json:"from"`
+		To      string  `json:"to"`
+		Expires string  `json:"expires"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	o.Base, o.Code, o.Rate = raw.Base, raw.Code, raw.Rate
+	for _, f := range []struct {
+		in  string
+		out *time.Time
+	}{
+		{raw.From, &o.From},
+		{raw.To, &o.To},
+		{raw.Expires, &o.Expires},
+	} {
+		t, err := parseOverrideTime(f.in)
+		if err != nil {
+			return err
+		}
+		*f.out = t
+	}
+	return nil
+}
+
+// Expired reports whether the override has expired at the given time.
+func (o *Override) Expired(now time.Time) bool {
+	return !o.Expires.IsZero() && now.After(o.Expires)
+}
+
+// Covers reports whether the override is effective on the given date. From
+// and To are taken as the dates they fall on in their own location.
+func (o *Override) Covers(date Date) bool {
+	if !o.From.IsZero() && date.Before(DateOf(o.From)) {
+		return false
+	}
+	if !o.To.IsZero() && date.After(DateOf(o.To)) {
+		return false
+	}
+	return true
+}
+
+// OverridesService holds manual rate overrides that are layered on top of the
+// rates returned by RatesService and HistoricalRatesService.
+type OverridesService struct {
+	mu        sync.RWMutex
+	overrides []*Override
+}
+
+// NewOverridesService creates a new handler for this service.
+func NewOverridesService() *OverridesService {
+	return &OverridesService{}
+}
+
+// Set adds the given overrides. An override with the same pair and date range
+// as an existing one replaces it.
+func (s *OverridesService) Set(overrides ...Override) error {
+	for _, o := range overrides {
+		if o.Base == "" || o.Code == "" {
+			return errors.New("override base and code must be passed")
+		}
+		if o.Rate <= 0 {
+			return errors.New("override rate must be positive")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range overrides {
+		o := overrides[i]
+		replaced := false
+		for j, existing := range s.overrides {
+			if existing.Base == o.Base && existing.Code == o.Code &&
+				existing.From.Equal(o.From) && existing.To.Equal(o.To) {
+				s.overrides[j] = &o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.overrides = append(s.overrides, &o)
+		}
+	}
+	return nil
+}
+
+// Remove deletes all overrides for the given base/code pair.
+func (s *OverridesService) Remove(base, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.overrides[:0]
+	for _, o := range s.overrides {
+		if o.Base != base || o.Code != code {
+			kept = append(kept, o)
+		}
+	}
+	s.overrides = kept
+}
+
+// Clear deletes all overrides.
+func (s *OverridesService) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides = nil
+}
+
+// List returns a copy of all overrides currently held, including expired ones.
+func (s *OverridesService) List() []Override {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Override, 0, len(s.overrides))
+	for _, o := range s.overrides {
+		list = append(list, *o)
+	}
+	return list
+}
+
+// Load reads a JSON array of overrides from r and adds them.
+func (s *OverridesService) Load(r io.Reader) error {
+	var overrides []Override
+	if err := json.NewDecoder(r).Decode(&overrides); err != nil {
+		return err
+	}
+	return s.Set(overrides...)
+}
+
+// LoadFile reads a JSON array of overrides from the file at path and adds them.
+func (s *OverridesService) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.Load(f)
+}
+
+// Lookup returns the override in effect for the given pair on the given date,
+// if any. When several overrides match, the most recently set one wins.
+func (s *OverridesService) Lookup(base, code string, date Date) (*Override, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for i := len(s.overrides) - 1; i >= 0; i-- {
+		o := s.overrides[i]
+		if o.Base == base && o.Code == code && !o.Expired(now) && o.Covers(date) {
+			found := *o
+			return &found, true
+		}
+	}
+	return nil, false
+}
+
+// apply returns rsp with any overrides in effect on date applied. The given
+// response is never modified, as it may be shared with the cache; a copy is
+// returned if anything was overridden.
+func (s *OverridesService) apply(rsp *RateResponse, date Date) *RateResponse {
+	if s == nil || rsp == nil {
+		return rsp
+	}
+
+	s.mu.RLock()
+	empty := len(s.overrides) == 0
+	s.mu.RUnlock()
+	if empty {
+		return rsp
+	}
+
+	var out *RateResponse
+	for _, code := range overrideCodes(s.List(), rsp.Base) {
+		o, ok := s.Lookup(rsp.Base, code, date)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = rsp.clone()
+		}
+		out.Rates[code] = o.Rate
+		out.Overridden = append(out.Overridden, code)
+	}
+
+	if out == nil {
+		return rsp
+	}
+	sort.Strings(out.Overridden)
+	return out
+}
+
+// overrideCodes returns the distinct codes overridden for the given base.
+func overrideCodes(overrides []Override, base string) []string {
+	seen := map[string]bool{}
+	codes := []string{}
+	for _, o := range overrides {
+		if o.Base == base && !seen[o.Code] {
+			seen[o.Code] = true
+			codes = append(codes, o.Code)
+		}
+	}
+	return codes
+}
+
+func parseOverrideTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,120 @@
+package dinero
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestOverrides_Lookup will test overrides are scoped by pair, date range and expiry.
+func TestOverrides_Lookup(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	overrides := NewOverridesService()
+	err := overrides.Set(
+		Override{
+			Base: "USD",
+			Code: "NZD",
+			Rate: 1.5,
+			From: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC),
+		},
+		Override{
+			Base:    "USD",
+			Code:    "AUD",
+			Rate:    1.2,
+			Expires: time.Now().Add(-1 * time.Hour),
+		},
+	)
+	Expect(err).Should(BeNil())
+
+	o, ok := overrides.Lookup("USD", "NZD", NewDate(2021, 1, 31))
+	Expect(ok).Should(BeTrue())
+	Expect(o.Rate).Should(Equal(1.5))
+
+	_, ok = overrides.Lookup("USD", "NZD", NewDate(2021, 2, 1))
+	Expect(ok).Should(BeFalse())
+
+	_, ok = overrides.Lookup("AUD", "NZD", NewDate(2021, 1, 15))
+	Expect(ok).Should(BeFalse())
+
+	// Expired overrides are ignored.
+	_, ok = overrides.Lookup("USD", "AUD", DateOf(time.Now()))
+	Expect(ok).Should(BeFalse())
+
+	// Overrides need a positive rate.
+	Expect(overrides.Set(Override{Base: "USD", Code: "GBP"})).ShouldNot(BeNil())
+}
+
+// TestOverrides_Load will test loading overrides from JSON.
+func TestOverrides_Load(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	overrides := NewOverridesService()
+	err := overrides.Load(strings.NewReader(`[
+		{"base": "USD", "code": "NZD", "rate": 1.5, "from": "2021-01-01", "to": "2021-01-31"},
+		{"base": "USD", "code": "AUD", "rate": 1.2, "expires": "2099-01-01T00:00:00Z"}
+	]`))
+	Expect(err).Should(BeNil())
+	Expect(overrides.List()).Should(HaveLen(2))
+
+	o, ok := overrides.Lookup("USD", "NZD", NewDate(2021, 1, 10))
+	Expect(ok).Should(BeTrue())
+	Expect(o.Rate).Should(Equal(1.5))
+
+	overrides.Remove("USD", "NZD")
+	Expect(overrides.List()).Should(HaveLen(1))
+
+	overrides.Clear()
+	Expect(overrides.List()).Should(BeEmpty())
+}
+
+// TestOverrides_Rates will test overrides are applied to rates responses without touching the cache.
+func TestOverrides_Rates(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"AUD": 1.35,
+		"NZD": 1.45,
+	}))
+	Expect(client.Overrides.Set(Override{Base: "USD", Code: "NZD", Rate: 1.5})).Should(BeNil())
+
+	rsp, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Rates).Should(HaveKeyWithValue("NZD", 1.5))
+	Expect(rsp.Rates).Should(HaveKeyWithValue("AUD", 1.35))
+	Expect(rsp.Overridden).Should(Equal([]string{"NZD"}))
+
+	rate, err := client.Rates.Get("NZD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.5))
+
+	// The cached response holds what OXR returned.
+	cached, ok := client.Cache.Get("USD", time.Now())
+	Expect(ok).Should(BeTrue())
+	Expect(cached.Rates).Should(HaveKeyWithValue("NZD", 1.45))
+	Expect(cached.Overridden).Should(BeEmpty())
+
+	// Historical lookups outside the override's range are untouched.
+	Expect(client.Overrides.Set(Override{
+		Base: "USD",
+		Code: "AUD",
+		Rate: 1.4,
+		From: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC),
+	})).Should(BeNil())
+
+	historical, err := client.HistoricalRates.Get("AUD", time.Date(2021, 1, 15, 12, 0, 0, 0, time.UTC))
+	Expect(err).Should(BeNil())
+	Expect(*historical).Should(Equal(1.4))
+
+	historical, err = client.HistoricalRates.Get("AUD", time.Date(2021, 2, 15, 12, 0, 0, 0, time.UTC))
+	Expect(err).Should(BeNil())
+	Expect(*historical).Should(Equal(1.35))
+}
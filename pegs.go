@@ -0,0 +1,196 @@
+package dinero
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// FixedRate is an irrevocable conversion factor between a currency and the
+// anchor currency it is fixed to.
+type FixedRate struct {
+	Code   string
+	Anchor string
+	// Factor is the number of units of Code per one unit of Anchor.
+	Factor float64
+	// Since is the date the factor took effect.
+	Since Date
+	// Legacy marks currencies that were replaced by the anchor (e.g. the
+	// euro-zone legacy currencies), as opposed to those pegged to it.
+	Legacy bool
+}
+
+var (
+	// ErrNotFixed is returned when converting between currencies that aren't
+	// fixed to one another.
+	ErrNotFixed = errors.New("no fixed conversion factor between currencies")
+)
+
+// fixedRates holds the irrevocable conversion factors we know of, keyed by
+// code. The euro-zone factors are those set by the Council of the European
+// Union on adoption of the euro.
+var fixedRates = map[string]FixedRate{
+	// Legacy euro-zone currencies.
+	"ATS": legacyEuro("ATS", 13.7603, 1999),
+	"BEF": legacyEuro("BEF", 40.3399, 1999),
+	"DEM": legacyEuro("DEM", 1.95583, 1999),
+	"ESP": legacyEuro("ESP", 166.386, 1999),
+	"FIM": legacyEuro("FIM", 5.94573, 1999),
+	"FRF": legacyEuro("FRF", 6.55957, 1999),
+	"IEP": legacyEuro("IEP", 0.787564, 1999),
+	"ITL": legacyEuro("ITL", 1936.27, 1999),
+	"LUF": legacyEuro("LUF", 40.3399, 1999),
+	"NLG": legacyEuro("NLG", 2.20371, 1999),
+	"PTE": legacyEuro("PTE", 200.482, 1999),
+	"GRD": legacyEuro("GRD", 340.750, 2001),
+	"SIT": legacyEuro("SIT", 239.640, 2007),
+	"CYP": legacyEuro("CYP", 0.585274, 2008),
+	"MTL": legacyEuro("MTL", 0.429300, 2008),
+	"SKK": legacyEuro("SKK", 30.1260, 2009),
+	"EEK": legacyEuro("EEK", 15.6466, 2011),
+	"LVL": legacyEuro("LVL", 0.702804, 2014),
+	"LTL": legacyEuro("LTL", 3.45280, 2015),
+	"HRK": legacyEuro("HRK", 7.53450, 2023),
+
+	// Currencies pegged to the euro.
+	"XOF": euroPeg("XOF", 655.957),
+	"XAF": euroPeg("XAF", 655.957),
+	"KMF": euroPeg("KMF", 491.96775),
+	"CVE": euroPeg("CVE", 110.265),
+	"XPF": euroPeg("XPF", 1000/8.38),
+	"BAM": euroPeg("BAM", 1.95583),
+}
+
+// LookupFixedRate returns the fixed conversion factor for code in effect on
+// the given date, if any.
+func LookupFixedRate(code string, date Date) (FixedRate, bool) {
+	fixed, ok := fixedRates[code]
+	if !ok || date.Before(fixed.Since) {
+		return FixedRate{}, false
+	}
+	return fixed, true
+}
+
+// FixedRates returns all known fixed conversion factors, sorted by code.
+func FixedRates() []FixedRate {
+	list := make([]FixedRate, 0, len(fixedRates))
+	for _, fixed := range fixedRates {
+		list = append(list, fixed)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// triangulationDecimals is the number of decimals the intermediate euro amount
+// is rounded to when triangulating between legacy euro-zone currencies. The
+// regulation requires not less than three; fewer leaves small amounts visibly
+// off.
+const triangulationDecimals = 6
+
+// ConvertFixed converts an amount between two currencies using only fixed
+// conversion factors in effect on the given date. Either currency may be the
+// anchor itself. Conversions between two currencies fixed to the same anchor
+// are triangulated through the anchor. Between two legacy euro-zone currencies
+// the intermediate euro amount is rounded to triangulationDecimals, as the
+// regulation requires; pegged currencies are converted exactly. Inverse rates
+// are never used.
+func ConvertFixed(amount float64, from, to string, date Date) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	fromFixed, fromOK := LookupFixedRate(from, date)
+	toFixed, toOK := LookupFixedRate(to, date)
+
+	switch {
+	case fromOK && fromFixed.Anchor == to:
+		return amount / fromFixed.Factor, nil
+	case toOK && toFixed.Anchor == from:
+		return amount * toFixed.Factor, nil
+	case fromOK && toOK && fromFixed.Anchor == toFixed.Anchor:
+		anchor := amount / fromFixed.Factor
+		if fromFixed.Legacy && toFixed.Legacy {
+			scale := math.Pow(10, triangulationDecimals)
+			anchor = math.Round(anchor*scale) / scale
+		}
+		return anchor * toFixed.Factor, nil
+	}
+	return 0, fmt.Errorf("%w: %s to %s", ErrNotFixed, from, to)
+}
+
+// applyFixedRates returns rsp with the rate for every currency pegged to a
+// currency in the table set from its conversion factor, replacing whatever
+// OXR returned. The legacy currencies replaced by their anchor are only added
+// if legacy is set; Client.lookup resolves them either way. The given response
+// is never modified; a copy is returned if anything was changed.
+func applyFixedRates(rsp *RateResponse, date Date, legacy bool) *RateResponse {
+	if rsp == nil {
+		return rsp
+	}
+
+	var out *RateResponse
+	for _, fixed := range FixedRates() {
+		if fixed.Code == rsp.Base || fixed.Legacy && !legacy {
+			continue
+		}
+
+		rate, ok := fixedRate(rsp, fixed.Code, date)
+		if !ok {
+			continue
+		}
+		if current, ok := rsp.Rates[fixed.Code]; ok && current == rate {
+			continue
+		}
+		if out == nil {
+			out = rsp.clone()
+		}
+		out.Rates[fixed.Code] = rate
+	}
+
+	if out == nil {
+		return rsp
+	}
+	return out
+}
+
+// fixedRate returns the rate for code derived from its conversion factor and
+// the rate for its anchor in rsp, if it has a factor in effect on the given
+// date and the anchor is in the table.
+func fixedRate(rsp *RateResponse, code string, date Date) (float64, bool) {
+	fixed, ok := LookupFixedRate(code, date)
+	if !ok {
+		return 0, false
+	}
+
+	anchorRate, ok := rsp.Rates[fixed.Anchor]
+	if fixed.Anchor == rsp.Base {
+		anchorRate, ok = 1, true
+	}
+	if !ok {
+		return 0, false
+	}
+	return anchorRate * fixed.Factor, true
+}
+
+func legacyEuro(code string, factor float64, year int) FixedRate {
+	return FixedRate{
+		Code:   code,
+		Anchor: "EUR",
+		Factor: factor,
+		Since:  NewDate(year, time.January, 1),
+		Legacy: true,
+	}
+}
+
+func euroPeg(code string, factor float64) FixedRate {
+	return FixedRate{
+		Code:   code,
+		Anchor: "EUR",
+		Factor: factor,
+		Since:  NewDate(1999, time.January, 1),
+	}
+}
@@ -0,0 +1,122 @@
+package dinero
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestFixedRates_Convert will test conversions using fixed factors, including EMU triangulation.
+func TestFixedRates_Convert(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	date := NewDate(2001, 6, 1)
+
+	amount, err := ConvertFixed(100, "EUR", "DEM", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 195.583, 1e-9))
+
+	amount, err = ConvertFixed(195.583, "DEM", "EUR", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 100, 1e-9))
+
+	// 100 DEM = 51.129188 EUR (rounded to 6 decimals) = 335.38... FRF.
+	amount, err = ConvertFixed(100, "DEM", "FRF", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 51.129188*6.55957, 1e-9))
+
+	// Small legacy amounts survive the rounding: 1000 ITL = 0.516457 EUR.
+	amount, err = ConvertFixed(1000, "ITL", "DEM", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 0.516457*1.95583, 1e-9))
+
+	// Pegs sharing the anchor aren't rounded.
+	amount, err = ConvertFixed(655.957, "XOF", "XAF", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 655.957, 1e-9))
+
+	amount, err = ConvertFixed(1, "XOF", "XAF", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 1, 1e-9))
+
+	amount, err = ConvertFixed(100, "XOF", "XAF", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 100, 1e-9))
+
+	amount, err = ConvertFixed(1, "XOF", "KMF", date)
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 491.96775/655.957, 1e-9))
+
+	// Greece only joined in 2001.
+	_, err = ConvertFixed(100, "GRD", "EUR", NewDate(2000, 6, 1))
+	Expect(errors.Is(err, ErrNotFixed)).Should(BeTrue())
+
+	_, err = ConvertFixed(100, "USD", "EUR", date)
+	Expect(errors.Is(err, ErrNotFixed)).Should(BeTrue())
+}
+
+// TestFixedRates_Rates will test fixed factors are derived from the anchor rate in rates responses.
+func TestFixedRates_Rates(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"EUR": 0.9,
+		"XOF": 591.2,
+	}))
+
+	rate, err := client.Rates.Get("DEM")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically("~", 0.9*1.95583, 1e-9))
+
+	// Drifting peg values from OXR are replaced.
+	rate, err = client.Rates.Get("XOF")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically("~", 0.9*655.957, 1e-9))
+
+	// Legacy currencies are resolved, but only listed if asked for.
+	rsp, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Rates).ShouldNot(HaveKey("DEM"))
+	Expect(rsp.Rates).Should(HaveKey("XAF"))
+
+	// As are custom currencies anchored to them.
+	Expect(client.CustomCurrencies.Register(CustomCurrency{
+		Code:   "MRK",
+		Anchor: "DEM",
+		Rate:   2,
+	})).Should(BeNil())
+	rate, err = client.Rates.Get("MRK")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically("~", 0.9*1.95583*2, 1e-9))
+
+	client.IncludeLegacy = true
+	rsp, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Rates).Should(HaveKeyWithValue("DEM", BeNumerically("~", 0.9*1.95583, 1e-9)))
+
+	// Conversions between pegs are exact.
+	amount, err := client.Rates.Convert(100, "XOF", "XAF")
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 100, 1e-9))
+
+	// Croatia only adopted the euro in 2023.
+	_, err = client.HistoricalRates.Get("HRK", time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	Expect(err).Should(Equal(ErrRatesNotFound))
+
+	rate, err = client.HistoricalRates.Get("HRK", time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC))
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically("~", 0.9*7.5345, 1e-9))
+
+	// With the anchor as the base, the factor is the rate.
+	client = newTestClient(t, "EUR", ratesHandler(map[string]float64{
+		"USD": 1.1,
+	}))
+	rate, err = client.Rates.Get("FRF")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(6.55957))
+}
@@ -0,0 +1,43 @@
+package dinero
+
+import "time"
+
+// RatesProvider provides the latest rates, as RatesService does from OXR.
+// Code using dinero can depend on it rather than the service, and substitute
+// StaticRates (or its own fake) in tests.
+type RatesProvider interface {
+	List() (*RateResponse, error)
+	Get(code string) (*float64, error)
+	Convert(amount float64, from, to string) (float64, error)
+	GetBaseCurrency() string
+	SetBaseCurrency(base string)
+}
+
+// HistoricalRatesProvider provides rates for past dates, as
+// HistoricalRatesService does from OXR.
+type HistoricalRatesProvider interface {
+	List(date time.Time) (*RateResponse, error)
+	ListOn(date Date) (*RateResponse, error)
+	Get(code string, date time.Time) (*float64, error)
+	GetOn(code string, date Date) (*float64, error)
+	Convert(amount float64, from, to string, date time.Time) (float64, error)
+	ConvertOn(amount float64, from, to string, date Date) (float64, error)
+	GetBaseCurrency() string
+	SetBaseCurrency(base string)
+}
+
+// CurrencyLister lists the available currencies, as CurrenciesService does
+// from OXR.
+type CurrencyLister interface {
+	List() ([]*CurrencyResponse, error)
+}
+
+var (
+	_ RatesProvider           = (*RatesService)(nil)
+	_ HistoricalRatesProvider = (*HistoricalRatesService)(nil)
+	_ CurrencyLister          = (*CurrenciesService)(nil)
+
+	_ RatesProvider           = (*StaticRates)(nil)
+	_ HistoricalRatesProvider = (*StaticHistoricalRates)(nil)
+	_ CurrencyLister          = (*StaticCurrencies)(nil)
+)
@@ -0,0 +1,323 @@
+package dinero
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQuotaBudgetExceeded is returned when a request would take the client
+	// past its QuotaGuard's budget for the billing period.
+	ErrQuotaBudgetExceeded = errors.New("quota budget exceeded")
+)
+
+// QuotaMode is what a QuotaGuard does once its budget is used up.
+type QuotaMode int
+
+const (
+	// QuotaBlock waits for the next billing period before sending requests,
+	// or until the request's context is done. If the period's end is
+	// unknown, it waits for the quota to be set or seeded again.
+	QuotaBlock QuotaMode = iota
+	// QuotaFail fails requests with ErrQuotaBudgetExceeded.
+	QuotaFail
+	// QuotaStale fails requests with ErrQuotaBudgetExceeded, but the rates
+	// services serve the last rates held (even if expired), or stored, instead.
+	QuotaStale
+)
+
+// QuotaGuard keeps the client within a budget of its OXR plan's request
+// quota, as going over it gets the app ID shut off. It counts the requests
+// made this billing period, seeded from usage.json whenever the client
+// fetches it, and optionally persisted so restarts don't lose count.
+// Requests for usage.json and currencies.json are free, and not counted.
+//
+// The guard keeps a single count for the client. With a KeyPool, it's seeded
+// only from the usage of the app ID in use, so it guards that key: usage
+// fetched for other keys (e.g. by Usage.RefreshKeys) is left to the pool.
+// Once the pool moves to another key, the count carries over until seeded
+// again, e.g. by calling Usage.Get.
+//
+//	guard := dinero.NewQuotaGuard(0.9, dinero.QuotaStale)
+//	if err := guard.Persist("/var/lib/myapp/oxr-quota.json"); err != nil {
+//		return err
+//	}
+//	client.Quota = guard
+//	client.Usage.Get() // Seed the quota and usage.
+type QuotaGuard struct {
+	mu        sync.Mutex
+	threshold float64
+	mode      QuotaMode
+	state     quotaState
+	path      string
+	// changed is closed, and replaced, whenever the quota is set or seeded.
+	changed chan struct{}
+}
+
+// quotaSaveEvery is how many requests a persisted QuotaGuard counts between
+// saves, until it's near its budget, from when it saves every one.
+const quotaSaveEvery = 10
+
+// quotaState is the persisted state of a QuotaGuard.
+type quotaState struct {
+	// Quota is the plan's request quota, or negative if unknown or
+	// unlimited.
+	Quota int64 `json:"quota"`
+	// Used is the number of requests made this billing period.
+	Used int64 `json:"used"`
+	// Resets is when the billing period ends, or the zero time if unknown.
+	Resets time.Time `json:"resets"`
+}
+
+// NewQuotaGuard creates a guard allowing the given fraction of the quota
+// (e.g. 0.9) to be used, after which requests are handled per mode. The
+// quota is unknown, and nothing is guarded, until set with SetQuota or
+// seeded from usage.json.
+func NewQuotaGuard(threshold float64, mode QuotaMode) *QuotaGuard {
+	return &QuotaGuard{
+		threshold: threshold,
+		mode:      mode,
+		state:     quotaState{Quota: -1},
+		changed:   make(chan struct{}),
+	}
+}
+
+// SetQuota will set the plan's request quota, the number of requests used
+// this billing period, and when it ends (the zero time if unknown).
+func (g *QuotaGuard) SetQuota(quota, used int64, resets time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.state = quotaState{Quota: quota, Used: used, Resets: resets}
+	g.notify()
+	return g.save()
+}
+
+// Persist will keep the guard's count in the file at path, loading it now if
+// the file exists. The count is saved every quotaSaveEvery requests, and
+// every request once near the budget; call Flush to save it on shutdown.
+func (g *QuotaGuard) Persist(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.path = path
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return g.save()
+	}
+	if err != nil {
+		return err
+	}
+	state := quotaState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("reading quota from %s: %w", path, err)
+	}
+	g.state = state
+	g.notify()
+	return nil
+}
+
+// Flush will save the guard's count to its file now, if it has one.
+func (g *QuotaGuard) Flush() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.save()
+}
+
+// Remaining returns the requests left in the budget this billing period, or
+// -1 if the quota is unknown or unlimited.
+func (g *QuotaGuard) Remaining() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.roll(time.Now())
+	budget := g.budget()
+	if budget < 0 {
+		return -1
+	}
+	if remaining := budget - g.state.Used; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// take counts a request against the budget, returning nil if it fits,
+// waiting for the next billing period first in QuotaBlock mode. Requests are
+// counted before they're sent, so concurrent requests can't overshoot.
+// Failures to save the count are reported to warn.
+func (g *QuotaGuard) take(ctx context.Context, warn func(error)) error {
+	for {
+		g.mu.Lock()
+		now := time.Now()
+		g.roll(now)
+		budget, used, resets, changed := g.budget(), g.state.Used, g.state.Resets, g.changed
+		if budget < 0 || used < budget {
+			g.state.Used++
+			var err error
+			if g.state.Used%quotaSaveEvery == 0 || budget >= 0 && budget-g.state.Used < quotaSaveEvery {
+				err = g.save()
+			}
+			g.mu.Unlock()
+			if err != nil {
+				warn(fmt.Errorf("saving quota: %w", err))
+			}
+			return nil
+		}
+		g.mu.Unlock()
+
+		err := fmt.Errorf("%w: %d of %d requests used", ErrQuotaBudgetExceeded, used, budget)
+		if g.mode != QuotaBlock {
+			return err
+		}
+
+		// Wait for the period to end, or for the quota to be set or seeded
+		// again, which is all there is to go on if its end is unknown.
+		var (
+			timer   *time.Timer
+			expired <-chan time.Time
+		)
+		if !resets.IsZero() {
+			timer = time.NewTimer(resets.Sub(now))
+			expired = timer.C
+		}
+		select {
+		case <-expired:
+		case <-changed:
+		case <-ctx.Done():
+			stopTimer(timer)
+			return fmt.Errorf("%v: %w", err, ctx.Err())
+		}
+		stopTimer(timer)
+	}
+}
+
+// seed records the usage reported by usage.json. The billing period is taken
+// to end at the start of the day after its last.
+func (g *QuotaGuard) seed(usage Usage) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.state = quotaState{
+		Quota:  usage.RequestsQuota,
+		Used:   usage.Requests,
+		Resets: usage.resets(time.Now()),
+	}
+	g.notify()
+	return g.save()
+}
+
+// stopTimer stops timer, if there is one.
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// notify wakes any requests waiting for the quota to change. The caller must
+// hold g.mu.
+func (g *QuotaGuard) notify() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// stale reports whether the rates services should serve stale rates for err.
+func (g *QuotaGuard) stale(err error) bool {
+	return g.servesStale() && errors.Is(err, ErrQuotaBudgetExceeded)
+}
+
+// servesStale reports whether the guard is in QuotaStale mode.
+func (g *QuotaGuard) servesStale() bool {
+	return g != nil && g.mode == QuotaStale
+}
+
+// admit waits for the client's Limiter and then counts the request against
+// its QuotaGuard, unless it's for an endpoint that doesn't count towards the
+// quota. A request given up on while waiting for the limiter isn't counted.
+func (c *Client) admit(req *http.Request, endpoint string) error {
+	if endpoint == usageEndpoint || endpoint == endpointOf(currenciesAPIPath) {
+		return nil
+	}
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(req.Context()); err != nil {
+			return err
+		}
+	}
+	if c.Quota != nil {
+		return c.Quota.take(req.Context(), c.warn)
+	}
+	return nil
+}
+
+// seedsQuota reports whether usage reported for key should seed the
+// client's QuotaGuard: only that of the app ID in use, when the client has a
+// KeyPool.
+func (c *Client) seedsQuota(key string) bool {
+	if pool, ok := c.Keys.(*KeyPool); ok {
+		return key == pool.inUse()
+	}
+	return true
+}
+
+// staleRates returns the rates to serve for base on date in place of err, if
+// the client's QuotaGuard is in QuotaStale mode and err is it refusing the
+// request: those held in the cache, even if expired, or else in the Store.
+// The zero date means the latest rates. Otherwise it returns err.
+func (c *Client) staleRates(base string, date Date, err error) (*RateResponse, error) {
+	if !c.Quota.stale(err) {
+		return nil, err
+	}
+	cached := date
+	if cached.IsZero() {
+		cached = c.today()
+	}
+	if rsp, ok := c.Cache.stale(base, cached); ok {
+		c.log(LevelWarn, "serving stale rates", baseField(base), Field{Key: "error", Value: err})
+		return rsp, nil
+	}
+	if rsp, storeErr := c.loadStored(base, date); storeErr == nil {
+		c.log(LevelWarn, "serving stored rates", baseField(base), Field{Key: "error", Value: err})
+		return rsp, nil
+	}
+	return nil, err
+}
+
+// budget returns the number of requests allowed this billing period, or -1
+// if there's no limit. The caller must hold g.mu.
+func (g *QuotaGuard) budget() int64 {
+	if g.state.Quota < 0 {
+		return -1
+	}
+	return int64(float64(g.state.Quota) * g.threshold)
+}
+
+// roll starts a new billing period if the last has ended. Its end is unknown
+// until seeded again. The caller must hold g.mu.
+func (g *QuotaGuard) roll(now time.Time) {
+	if g.state.Resets.IsZero() || now.Before(g.state.Resets) {
+		return
+	}
+	g.state.Used = 0
+	g.state.Resets = time.Time{}
+	_ = g.save()
+}
+
+// save writes the guard's state to its file, if it has one. The caller must
+// hold g.mu.
+func (g *QuotaGuard) save() error {
+	if g.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(&g.state)
+	if err != nil {
+		return err
+	}
+	return writeFile(g.path, data)
+}
@@ -0,0 +1,220 @@
+package dinero
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// TestQuotaGuard will test the guard failing requests once its budget is used.
+func TestQuotaGuard(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetQuota(10)
+
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Quota = NewQuotaGuard(0.5, QuotaFail)
+
+	// Nothing is guarded until the quota is known.
+	Expect(client.Quota.Remaining()).Should(Equal(int64(-1)))
+
+	// Usage is seeded from usage.json, which doesn't count.
+	_, err := client.Usage.Get()
+	Expect(err).Should(BeNil())
+	Expect(client.Quota.Remaining()).Should(Equal(int64(5)))
+
+	for i := 0; i < 5; i++ {
+		_, err = client.HistoricalRates.ListOn(Date{Year: 2021, Month: time.January, Day: i + 1})
+		Expect(err).Should(BeNil())
+	}
+	Expect(client.Quota.Remaining()).Should(Equal(int64(0)))
+
+	// The next request is refused without calling OXR.
+	before := len(server.Requests())
+	_, err = client.Rates.List()
+	Expect(errors.Is(err, ErrQuotaBudgetExceeded)).Should(BeTrue())
+	Expect(server.Requests()).Should(HaveLen(before))
+
+	// Currencies are free.
+	_, err = client.Currencies.List()
+	Expect(err).Should(BeNil())
+
+	// A new billing period starts afresh.
+	Expect(client.Quota.SetQuota(10, 0, time.Now().Add(time.Hour))).Should(BeNil())
+	_, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(client.Quota.Remaining()).Should(Equal(int64(4)))
+}
+
+// TestQuotaGuard_KeyPool will test the guard only being seeded from the app ID in use.
+func TestQuotaGuard_KeyPool(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetQuota(10)
+
+	pool, err := NewKeyPool(StaticKeys("first-key", "second-key"))
+	Expect(err).Should(BeNil())
+
+	client := NewClient("", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Keys = pool
+	client.Quota = NewQuotaGuard(1, QuotaFail)
+
+	// Usage of other keys is left to the pool.
+	_, err = client.Usage.GetFor("second-key")
+	Expect(err).Should(BeNil())
+	Expect(client.Quota.Remaining()).Should(Equal(int64(-1)))
+	Expect(pool.Usage()[1].Quota).Should(Equal(int64(10)))
+
+	// That of the key in use seeds the guard.
+	Expect(client.Usage.RefreshKeys()).Should(BeNil())
+	Expect(client.Quota.Remaining()).Should(Equal(int64(10)))
+}
+
+// TestQuotaGuard_Stale will test serving expired rates once the budget is used.
+func TestQuotaGuard_Stale(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Quota = NewQuotaGuard(1, QuotaStale)
+	Expect(client.Quota.SetQuota(1, 0, time.Time{})).Should(BeNil())
+	client.Cache.SetTTL(time.Millisecond, 0)
+
+	rsp, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	rate := rsp.Rates["GBP"]
+
+	// Expired rates are served rather than an error.
+	time.Sleep(5 * time.Millisecond)
+
+	single, err := client.Rates.Get("GBP")
+	Expect(err).Should(BeNil())
+	Expect(*single).Should(Equal(rate))
+	Expect(server.Requests()).Should(HaveLen(1))
+
+	// With nothing held, the error is returned.
+	_, err = client.HistoricalRates.ListOn(Date{Year: 2021, Month: time.January, Day: 1})
+	Expect(errors.Is(err, ErrQuotaBudgetExceeded)).Should(BeTrue())
+}
+
+// TestQuotaGuard_Block will test waiting for the next billing period.
+func TestQuotaGuard_Block(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Quota = NewQuotaGuard(1, QuotaBlock)
+
+	// Requests wait for the period to reset.
+	Expect(client.Quota.SetQuota(1, 1, time.Now().Add(50*time.Millisecond))).Should(BeNil())
+	start := time.Now()
+	_, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(time.Since(start)).Should(BeNumerically(">=", 40*time.Millisecond))
+
+	// Or until their context is done.
+	Expect(client.Quota.SetQuota(1, 1, time.Now().Add(time.Hour))).Should(BeNil())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.HistoricalRates.ListOnContext(ctx, Date{Year: 2021, Month: time.January, Day: 1})
+	Expect(errors.Is(err, context.DeadlineExceeded)).Should(BeTrue())
+
+	// With the period's end unknown, they wait for the quota to be seeded.
+	Expect(client.Quota.SetQuota(1, 1, time.Time{})).Should(BeNil())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = client.Usage.Get()
+	}()
+	start = time.Now()
+	_, err = client.HistoricalRates.ListOn(Date{Year: 2021, Month: time.January, Day: 2})
+	Expect(err).Should(BeNil())
+	Expect(time.Since(start)).Should(BeNumerically(">=", 40*time.Millisecond))
+}
+
+// TestQuotaGuard_Limiter will test requests given up on by the limiter not being counted.
+func TestQuotaGuard_Limiter(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Limiter = NewRateLimiter(1, time.Hour, 1)
+	client.Quota = NewQuotaGuard(1, QuotaFail)
+	Expect(client.Quota.SetQuota(10, 0, time.Time{})).Should(BeNil())
+
+	_, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(client.Quota.Remaining()).Should(Equal(int64(9)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.HistoricalRates.ListOnContext(ctx, Date{Year: 2021, Month: time.January, Day: 1})
+	Expect(errors.Is(err, context.DeadlineExceeded)).Should(BeTrue())
+	Expect(client.Quota.Remaining()).Should(Equal(int64(9)))
+}
+
+// TestQuotaGuard_Persist will test the count surviving a restart.
+func TestQuotaGuard_Persist(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "quota.json")
+	resets := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+
+	guard := NewQuotaGuard(1, QuotaFail)
+	Expect(guard.Persist(path)).Should(BeNil())
+	Expect(guard.SetQuota(10, 3, resets)).Should(BeNil())
+	Expect(guard.take(context.Background(), nil)).Should(BeNil())
+
+	restarted := NewQuotaGuard(1, QuotaFail)
+	Expect(restarted.Persist(path)).Should(BeNil())
+	Expect(restarted.Remaining()).Should(Equal(int64(6)))
+
+	// Far from the budget, the count is only saved every so often.
+	Expect(guard.SetQuota(1000, 3, resets)).Should(BeNil())
+	for i := 0; i < 5; i++ {
+		Expect(guard.take(context.Background(), nil)).Should(BeNil())
+	}
+	restarted = NewQuotaGuard(1, QuotaFail)
+	Expect(restarted.Persist(path)).Should(BeNil())
+	Expect(restarted.Remaining()).Should(Equal(int64(997)))
+
+	for i := 0; i < 2; i++ {
+		Expect(guard.take(context.Background(), nil)).Should(BeNil())
+	}
+	restarted = NewQuotaGuard(1, QuotaFail)
+	Expect(restarted.Persist(path)).Should(BeNil())
+	Expect(restarted.Remaining()).Should(Equal(int64(990)))
+
+	// Or when flushed.
+	Expect(guard.take(context.Background(), nil)).Should(BeNil())
+	Expect(guard.Flush()).Should(BeNil())
+	restarted = NewQuotaGuard(1, QuotaFail)
+	Expect(restarted.Persist(path)).Should(BeNil())
+	Expect(restarted.Remaining()).Should(Equal(int64(989)))
+}
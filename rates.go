@@ -1,8 +1,10 @@
 package dinero
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"time"
 )
@@ -30,37 +32,141 @@ func NewRatesService(
 
 // RateResponse holds our forex rates for a given base currency
 type RateResponse struct {
-	Rates     map[string]float64 `json:"rates"`
-	Base      string             `json:"base"`
-	Timestamp int64              `json:"timestamp"`
+	Rates map[string]float64 `json:"rates"`
+	Base  string             `json:"base"`
+	// Timestamp is when OXR published the rates, in seconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+	// FetchedAt is when the rates were fetched from OXR, in seconds since the
+	// epoch.
+	FetchedAt int64 `json:"fetched_at,omitempty"`
+	// Date is the day historical rates are for.
+	Date string `json:"date,omitempty"`
+	// Overridden lists the codes whose rates were replaced by an Override.
+	Overridden []string `json:"overridden,omitempty"`
+}
+
+// PublishedAt returns when OXR published the rates, or the zero time if
+// that's unknown.
+func (r *RateResponse) PublishedAt() time.Time {
+	return unixTime(r.Timestamp)
+}
+
+// Fetched returns when the rates were fetched from OXR, or the zero time if
+// that's unknown.
+func (r *RateResponse) Fetched() time.Time {
+	return unixTime(r.FetchedAt)
+}
+
+// Age returns how long ago OXR published the rates, or zero if that's
+// unknown.
+func (r *RateResponse) Age() time.Duration {
+	if r.Timestamp == 0 {
+		return 0
+	}
+	return time.Since(r.PublishedAt())
+}
+
+// clone returns a copy of the response that can be modified without
+// affecting the original (e.g. the cached copy).
+func (r *RateResponse) clone() *RateResponse {
+	out := *r
+	out.Rates = make(map[string]float64, len(r.Rates))
+	for code, rate := range r.Rates {
+		out.Rates[code] = rate
+	}
+	out.Overridden = append([]string(nil), r.Overridden...)
+	return &out
 }
 
 // List will fetch all the latest rates for the base currency either from the store or the OXR api.
 func (s *RatesService) List() (*RateResponse, error) {
-	// If we have cached results, use them.
-	if results, ok := s.client.Cache.Get(s.baseCurrency, time.Now()); ok {
-		return results, nil
+	return s.ListContext(context.Background())
+}
+
+// ListContext is like List, tracing the call within ctx.
+func (s *RatesService) ListContext(ctx context.Context) (rsp *RateResponse, err error) {
+	ctx, span := s.client.startSpan(ctx, "dinero.Rates.List", baseAttribute(s.baseCurrency))
+	defer func() { endSpan(span, err) }()
+
+	return s.list(ctx)
+}
+
+func (s *RatesService) list(ctx context.Context) (*RateResponse, error) {
+	results, err := s.latest(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return s.client.decorate(results, s.client.today()), nil
+}
 
-	// No cached results, go and fetch them.
-	if err := s.fetch(); err != nil {
+// latest returns the latest rates for the base currency, from the cache or
+// else fetched. Cached rates older than the client's MaxAge are refreshed,
+// and only rejected with ErrStaleRates if they're still too old.
+func (s *RatesService) latest(ctx context.Context) (*RateResponse, error) {
+	// If we have cached results that are fresh enough, use them.
+	today := s.client.today()
+	cached, ok := s.client.Cache.getOn(ctx, s.baseCurrency, today)
+	if ok && s.client.checkAge(cached) == nil {
+		return cached, nil
+	}
+
+	// Otherwise, go and fetch them.
+	if err := s.fetch(ctx); err != nil {
+		results, staleErr := s.client.staleRates(s.baseCurrency, Date{}, err)
+		if staleErr == nil {
+			return results, nil
+		}
+		if ok {
+			return nil, fmt.Errorf("%w (refreshing: %v)", s.client.checkAge(cached), err)
+		}
 		return nil, err
 	}
 
-	return s.List()
+	results, ok := s.client.Cache.getOn(ctx, s.baseCurrency, today)
+	if !ok {
+		return nil, ErrRatesNotFound
+	}
+	if err := s.client.checkAge(results); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-// ListHistorical will fetch all rates for the base currency for the given time.Time.
+// ListHistorical will fetch all rates for the base currency for the day the
+// given time.Time falls on in the client's Location.
 func (s *RatesService) ListHistorical(date time.Time) (*RateResponse, error) {
+	return s.ListHistoricalOn(s.client.dateOf(date))
+}
+
+// ListHistoricalOn is like ListHistorical, but for a calendar date.
+func (s *RatesService) ListHistoricalOn(date Date) (*RateResponse, error) {
+	if err := s.client.checkBase(s.baseCurrency); err != nil {
+		return nil, err
+	}
+
+	// Offline, serve the stored rates for the date.
+	if s.client.Offline {
+		response, err := s.client.loadStored(s.baseCurrency, date)
+		if err != nil {
+			return nil, err
+		}
+		return s.client.decorate(response, date), nil
+	}
+
 	// add `base` query param if it is not empty.
 	params := url.Values{}
 	if s.baseCurrency != "" {
 		params.Set("base", s.baseCurrency)
 	}
 
+	// Don't repeat a request that's already failed for good.
+	if err := s.client.Cache.failure(s.baseCurrency, date); err != nil {
+		return nil, err
+	}
+
 	request, err := s.client.NewRequest(
 		"GET",
-		fmt.Sprintf(historicalAPIPath, date.Format("2006-01-02")),
+		fmt.Sprintf(historicalAPIPath, date),
 		params,
 		nil,
 	)
@@ -70,33 +176,51 @@ func (s *RatesService) ListHistorical(date time.Time) (*RateResponse, error) {
 
 	response := &RateResponse{}
 	if _, err := s.client.Do(request, response); err != nil {
-		return nil, err
+		s.client.Cache.fail(s.baseCurrency, date, err)
+		if response, err = s.client.staleRates(s.baseCurrency, date, err); err != nil {
+			return nil, err
+		}
+		return s.client.decorate(response, date), nil
 	}
+	response.FetchedAt = time.Now().Unix()
 
-	return response, nil
+	return s.client.decorate(response, date), nil
 }
 
 // Get will fetch a single rate for a given currency either from the store or the OXR api.
 func (s *RatesService) Get(code string) (*float64, error) {
+	return s.GetContext(context.Background(), code)
+}
+
+// GetContext is like Get, tracing the call within ctx.
+func (s *RatesService) GetContext(ctx context.Context, code string) (rate *float64, err error) {
+	ctx, span := s.client.startSpan(ctx, "dinero.Rates.Get",
+		baseAttribute(s.baseCurrency),
+		Attribute{Key: AttributeSymbols, Value: code},
+	)
+	defer func() { endSpan(span, err) }()
+
+	return s.get(ctx, code)
+}
+
+func (s *RatesService) get(ctx context.Context, code string) (*float64, error) {
 	// No code passed, let them know!
 	if code == "" {
 		return nil, errors.New("currency code must be passed")
 	}
 
-	// If we have cached results, use them.
-	if results, ok := s.client.Cache.Get(s.baseCurrency, time.Now()); ok {
-		if single, ok := results.Rates[code]; ok {
-			return &single, nil
-		}
-		return nil, ErrRatesNotFound
-	}
-
-	// No cached results, go and fetch them.
-	if err := s.fetch(); err != nil {
+	results, err := s.latest(ctx)
+	if err != nil {
 		return nil, err
 	}
+	today := s.client.today()
+	results = s.client.decorate(results, today)
+	return s.client.lookup(results, code, today)
+}
 
-	return s.Get(code)
+// Convert will convert an amount between two currencies using the latest rates.
+func (s *RatesService) Convert(amount float64, from, to string) (float64, error) {
+	return convert(amount, from, to, s.client.today(), s.Get)
 }
 
 // GetBaseCurrency will return the baseCurrency.
@@ -109,7 +233,28 @@ func (s *RatesService) SetBaseCurrency(base string) {
 	s.baseCurrency = base
 }
 
-func (s *RatesService) fetch() error {
+func (s *RatesService) fetch(ctx context.Context) error {
+	if err := s.client.checkBase(s.baseCurrency); err != nil {
+		return err
+	}
+
+	// Offline, serve the most recent stored rates.
+	if s.client.Offline {
+		latest, err := s.client.loadStored(s.baseCurrency, Date{})
+		if err != nil {
+			return err
+		}
+		s.SetBaseCurrency(latest.Base)
+		s.client.Cache.storeOn(latest, s.client.today(), nil)
+		return nil
+	}
+
+	// Don't repeat a request that's already failed for good.
+	today := s.client.today()
+	if err := s.client.Cache.failure(s.baseCurrency, today); err != nil {
+		return err
+	}
+
 	// Build request.
 	// add `base` query param if it is not empty
 	params := url.Values{}
@@ -125,17 +270,61 @@ func (s *RatesService) fetch() error {
 	if err != nil {
 		return err
 	}
+	request = request.WithContext(ctx)
+	validated := s.client.Cache.validate(request, s.baseCurrency, today)
 
 	// Make request
+	s.client.log(LevelInfo, "refreshing latest rates", baseField(s.baseCurrency))
 	var latest *RateResponse
-	if _, err := s.client.Do(request, &latest); err != nil {
+	response, err := s.client.Do(request, &latest)
+	if err != nil {
+		s.client.log(LevelWarn, "refreshing latest rates failed", baseField(s.baseCurrency), Field{Key: "error", Value: err})
+		s.client.Cache.fail(s.baseCurrency, today, err)
 		return err
 	}
 
+	// The expired rates we hold are still current.
+	if response.StatusCode == http.StatusNotModified {
+		s.client.log(LevelInfo, "latest rates not modified", baseField(s.baseCurrency))
+		if !validated || !s.client.Cache.revalidate(s.baseCurrency, today) {
+			return ErrNothingToRevalidate
+		}
+		return nil
+	}
+	latest.FetchedAt = time.Now().Unix()
+	s.client.log(LevelInfo, "refreshed latest rates", baseField(latest.Base), Field{Key: "published", Value: latest.PublishedAt()})
+
 	s.SetBaseCurrency(latest.Base)
 
-	// Store our results.
-	s.client.Cache.Store(latest, time.Now())
+	// Persist and store our results.
+	if err := s.client.persist(latest, today); err != nil {
+		return err
+	}
+	s.client.Cache.storeOn(latest, today, response.Header)
 
 	return nil
 }
+
+// size estimates the memory held by the response, in bytes.
+func (r *RateResponse) size() int64 {
+	// rateSize approximates a rate's share of the map, beyond its code.
+	const rateSize = 48
+
+	size := int64(128 + len(r.Base) + len(r.Date))
+	for code := range r.Rates {
+		size += int64(len(code) + rateSize)
+	}
+	for _, code := range r.Overridden {
+		size += int64(len(code) + 16)
+	}
+	return size
+}
+
+// unixTime returns the time for a number of seconds since the epoch, or the
+// zero time for zero.
+func unixTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
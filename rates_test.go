@@ -1,11 +1,14 @@
 package dinero
 
 import (
+	"errors"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/mattevans/dinero/dinerotest"
 	. "github.com/onsi/gomega"
 )
 
@@ -15,7 +18,7 @@ func TestRatesDefaultCurrency_List(t *testing.T) {
 	NewWithT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, "", 1*time.Minute)
+	client := newClient(t, "")
 
 	// Get latest forex rates.
 	response, err := client.Rates.List()
@@ -38,7 +41,7 @@ func TestRatesDefaultCurrency_Get(t *testing.T) {
 	NewWithT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, "", 1*time.Minute)
+	client := newClient(t, "")
 
 	// Get latest forex rates for NZD (using defaultCurrency as a base).
 	response, err := client.Rates.Get("NZD")
@@ -58,7 +61,7 @@ func TestRates_List(t *testing.T) {
 	NewWithT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, "AUD", 1*time.Minute)
+	client := newClient(t, "AUD")
 
 	// Get latest forex rates.
 	response, err := client.Rates.List()
@@ -84,7 +87,7 @@ func TestRates_Get(t *testing.T) {
 	NewWithT(t)
 
 	// Init dinero client.
-	client := NewClient(appID, "AUD", 1*time.Minute)
+	client := newClient(t, "AUD")
 
 	// Get latest forex rates for NZD (using AUD as a base).
 	response, err := client.Rates.Get("NZD")
@@ -100,3 +103,69 @@ func TestRates_Get(t *testing.T) {
 		t.Fatalf("Unexpected rate datatype, expected float64 got %T", response)
 	}
 }
+
+// TestRates_Freshness will test keeping the published timestamp and rejecting rates older than MaxAge.
+func TestRates_Freshness(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{"AUD": 1.3}))
+
+	before := time.Now().Unix()
+	rsp, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Timestamp).Should(Equal(int64(1640995200)))
+	Expect(rsp.PublishedAt()).Should(Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)))
+	Expect(rsp.FetchedAt).Should(BeNumerically(">=", before))
+	Expect(rsp.Age()).Should(BeNumerically(">", 24*time.Hour))
+
+	// The cached copy keeps the published timestamp.
+	cached, ok := client.Cache.Get("USD", time.Now())
+	Expect(ok).Should(BeTrue())
+	Expect(cached.Timestamp).Should(Equal(int64(1640995200)))
+
+	client.MaxAge = 1 * time.Hour
+	_, err = client.Rates.List()
+	Expect(errors.Is(err, ErrStaleRates)).Should(BeTrue())
+	_, err = client.Rates.Get("AUD")
+	Expect(errors.Is(err, ErrStaleRates)).Should(BeTrue())
+
+	// Historical rates are old by nature, so aren't checked.
+	_, err = client.HistoricalRates.Get("AUD", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	Expect(err).Should(BeNil())
+
+	// Rates with no published time aren't taken to be stale.
+	unknown := &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.3}}
+	Expect(unknown.Age()).Should(BeZero())
+	Expect(client.checkAge(unknown)).Should(BeNil())
+	unknown.FetchedAt = time.Now().Add(-2 * time.Hour).Unix()
+	Expect(errors.Is(client.checkAge(unknown), ErrStaleRates)).Should(BeTrue())
+}
+
+// TestRates_FreshnessRefresh will test refreshing rates older than MaxAge before rejecting them.
+func TestRates_FreshnessRefresh(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetLatest(map[string]float64{"AUD": 1.3}, time.Now().Add(-2*time.Hour))
+
+	client := NewClient("12345", "USD", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	_, err := client.Rates.List()
+	Expect(err).Should(BeNil())
+
+	// The cached rates are too old, and so are OXR's.
+	client.MaxAge = time.Hour
+	_, err = client.Rates.Get("AUD")
+	Expect(errors.Is(err, ErrStaleRates)).Should(BeTrue())
+	Expect(server.Requests()).Should(HaveLen(2))
+
+	// Once OXR publishes newer rates, they're used.
+	server.SetLatest(map[string]float64{"AUD": 1.4}, time.Now())
+	rate, err := client.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.4))
+	Expect(server.Requests()).Should(HaveLen(3))
+}
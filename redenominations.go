@@ -0,0 +1,125 @@
+package dinero
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Redenomination records a currency code being succeeded by a new one.
+type Redenomination struct {
+	From string
+	To   string
+	// Effective is the date the new code replaced the old one.
+	Effective Date
+	// Factor is the number of units of From per one unit of To.
+	Factor float64
+}
+
+var (
+	// ErrNotRedenominated is returned when converting between codes that
+	// aren't part of the same succession history.
+	ErrNotRedenominated = errors.New("currencies are not related by redenomination")
+)
+
+// CodeNotActiveError reports that a currency code was not in use on the
+// requested date, and which code was used in its place.
+type CodeNotActiveError struct {
+	Code   string
+	Active string
+	Date   Date
+}
+
+func (e *CodeNotActiveError) Error() string {
+	return fmt.Sprintf("%s was not in use on %s, using %s", e.Code, e.Date, e.Active)
+}
+
+// redenominations holds the succession history of redenominated currencies,
+// in order of effective date.
+var redenominations = []Redenomination{
+	{From: "ZWD", To: "ZWN", Effective: NewDate(2006, time.August, 1), Factor: 1e3},
+	{From: "VEB", To: "VEF", Effective: NewDate(2008, time.January, 1), Factor: 1e3},
+	{From: "ZWN", To: "ZWR", Effective: NewDate(2008, time.August, 1), Factor: 1e10},
+	{From: "ZWR", To: "ZWL", Effective: NewDate(2009, time.February, 2), Factor: 1e12},
+	{From: "BYR", To: "BYN", Effective: NewDate(2016, time.July, 1), Factor: 1e4},
+	{From: "MRO", To: "MRU", Effective: NewDate(2018, time.January, 1), Factor: 10},
+	{From: "STD", To: "STN", Effective: NewDate(2018, time.January, 1), Factor: 1e3},
+	{From: "VEF", To: "VES", Effective: NewDate(2018, time.August, 20), Factor: 1e5},
+}
+
+// Redenominations returns the succession history of redenominated currencies.
+func Redenominations() []Redenomination {
+	return append([]Redenomination(nil), redenominations...)
+}
+
+// ResolveCode returns the code that was in use on the given date for the
+// currency identified by code, along with the number of units of code per one
+// unit of the active code. Codes with no succession history resolve to
+// themselves with a factor of 1.
+func ResolveCode(code string, date Date) (string, float64) {
+	active, factor := code, 1.0
+
+	// Walk forward through any successors already in effect.
+	for {
+		next, ok := successor(active)
+		if !ok || date.Before(next.Effective) {
+			break
+		}
+		active, factor = next.To, factor*next.Factor
+	}
+	if active != code {
+		return active, factor
+	}
+
+	// Walk back through any predecessors, if code wasn't in effect yet.
+	for {
+		prev, ok := predecessor(active)
+		if !ok || !date.Before(prev.Effective) {
+			break
+		}
+		active, factor = prev.From, factor/prev.Factor
+	}
+	return active, factor
+}
+
+// Redenominate converts an amount between two codes from the same succession
+// history, e.g. 1,000,000 VEF to 10 VES.
+func Redenominate(amount float64, from, to string) (float64, error) {
+	fromRoot, fromFactor := succession(from)
+	toRoot, toFactor := succession(to)
+	if fromRoot != toRoot {
+		return 0, fmt.Errorf("%w: %s to %s", ErrNotRedenominated, from, to)
+	}
+	return amount * fromFactor / toFactor, nil
+}
+
+// succession returns the earliest code in the succession history of code,
+// along with the number of units of that code per one unit of code.
+func succession(code string) (string, float64) {
+	root, factor := code, 1.0
+	for {
+		prev, ok := predecessor(root)
+		if !ok {
+			return root, factor
+		}
+		root, factor = prev.From, factor*prev.Factor
+	}
+}
+
+func successor(code string) (Redenomination, bool) {
+	for _, r := range redenominations {
+		if r.From == code {
+			return r, true
+		}
+	}
+	return Redenomination{}, false
+}
+
+func predecessor(code string) (Redenomination, bool) {
+	for _, r := range redenominations {
+		if r.To == code {
+			return r, true
+		}
+	}
+	return Redenomination{}, false
+}
@@ -0,0 +1,99 @@
+package dinero
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestRedenominations_ResolveCode will test resolving a code to whichever code was in use on a date.
+func TestRedenominations_ResolveCode(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	active, factor := ResolveCode("VEF", NewDate(2020, 1, 1))
+	Expect(active).Should(Equal("VES"))
+	Expect(factor).Should(Equal(1e5))
+
+	active, factor = ResolveCode("VES", NewDate(2017, 1, 1))
+	Expect(active).Should(Equal("VEF"))
+	Expect(factor).Should(Equal(1e-5))
+
+	active, factor = ResolveCode("ZWD", NewDate(2010, 1, 1))
+	Expect(active).Should(Equal("ZWL"))
+	Expect(factor).Should(Equal(1e25))
+
+	active, factor = ResolveCode("ZWD", NewDate(2007, 1, 1))
+	Expect(active).Should(Equal("ZWN"))
+	Expect(factor).Should(Equal(1e3))
+
+	active, factor = ResolveCode("ZWL", NewDate(2007, 1, 1))
+	Expect(active).Should(Equal("ZWN"))
+	Expect(factor).Should(BeNumerically("~", 1e-22, 1e-36))
+
+	active, factor = ResolveCode("BYN", NewDate(2016, 7, 1))
+	Expect(active).Should(Equal("BYN"))
+	Expect(factor).Should(Equal(1.0))
+
+	active, factor = ResolveCode("AUD", NewDate(2016, 7, 1))
+	Expect(active).Should(Equal("AUD"))
+	Expect(factor).Should(Equal(1.0))
+
+	// The history is in order of effective date.
+	history := Redenominations()
+	for i := 1; i < len(history); i++ {
+		Expect(history[i].Effective.Before(history[i-1].Effective)).Should(BeFalse())
+	}
+}
+
+// TestRedenominations_Redenominate will test converting amounts across redenomination boundaries.
+func TestRedenominations_Redenominate(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	amount, err := Redenominate(1000000, "VEF", "VES")
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 10, 1e-9))
+
+	amount, err = Redenominate(10, "VES", "VEB")
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 1e9, 1e-3))
+
+	amount, err = Redenominate(1e25, "ZWD", "ZWL")
+	Expect(err).Should(BeNil())
+	Expect(amount).Should(BeNumerically("~", 1, 1e-9))
+
+	_, err = Redenominate(10, "VES", "MRU")
+	Expect(errors.Is(err, ErrNotRedenominated)).Should(BeTrue())
+}
+
+// TestRedenominations_HistoricalRates will test historical lookups resolve codes across redenominations.
+func TestRedenominations_HistoricalRates(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"VES": 4.5,
+	}))
+
+	var warnings []error
+	client.OnWarning = func(err error) {
+		warnings = append(warnings, err)
+	}
+
+	date := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	rate, err := client.HistoricalRates.Get("VEF", date)
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(BeNumerically("~", 4.5e5, 1e-6))
+
+	Expect(warnings).Should(HaveLen(1))
+	Expect(warnings[0]).Should(Equal(&CodeNotActiveError{Code: "VEF", Active: "VES", Date: NewDate(2020, 1, 1)}))
+
+	rate, err = client.HistoricalRates.Get("VES", date)
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(4.5))
+	Expect(warnings).Should(HaveLen(1))
+}
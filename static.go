@@ -0,0 +1,258 @@
+package dinero
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateTable is a fixed set of rates, served from memory by NewStatic.
+type RateTable struct {
+	// Base is the currency the rates are per, USD if empty.
+	Base string
+	// Latest are the latest rates.
+	Latest map[string]float64
+	// Published is when the latest rates were published, the zero time if
+	// unknown.
+	Published time.Time
+	// Historical are the rates for past dates.
+	Historical map[Date]map[string]float64
+	// Currencies maps the available currency codes to their names.
+	Currencies map[string]string
+	// Location is the time zone that times are converted to dates in, UTC if
+	// nil.
+	Location *time.Location
+}
+
+// Static serves a RateTable through the same interfaces as the Client's
+// services, for tests and offline tools that shouldn't make HTTP requests.
+//
+//	static := dinero.NewStatic(dinero.RateTable{
+//		Latest: map[string]float64{"AUD": 1.25, "NZD": 1.5},
+//	})
+//	var rates dinero.RatesProvider = static.Rates
+type Static struct {
+	Rates           *StaticRates
+	HistoricalRates *StaticHistoricalRates
+	Currencies      *StaticCurrencies
+}
+
+// NewStatic creates static services for a copy of the table. They behave
+// like the Client's: rates can be had in any base the table has a rate for,
+// fixed rates and redenominations apply, the base currency is always 1, and
+// codes without a rate (like dates without rates) return ErrRatesNotFound.
+func NewStatic(table RateTable) *Static {
+	t := &staticTable{
+		base:       table.Base,
+		latest:     copyRates(table.Latest),
+		published:  table.Published,
+		historical: make(map[Date]map[string]float64, len(table.Historical)),
+		currencies: make(map[string]string, len(table.Currencies)),
+		location:   table.Location,
+	}
+	if t.base == "" {
+		t.base = defaultBaseCurrency
+	}
+	if t.location == nil {
+		t.location = time.UTC
+	}
+	for date, rates := range table.Historical {
+		t.historical[date] = copyRates(rates)
+	}
+	for code, name := range table.Currencies {
+		t.currencies[code] = name
+	}
+
+	return &Static{
+		Rates:           &StaticRates{table: t, baseCurrency: t.base},
+		HistoricalRates: &StaticHistoricalRates{table: t, baseCurrency: t.base},
+		Currencies:      &StaticCurrencies{table: t},
+	}
+}
+
+// staticTable is the RateTable shared by a Static's services.
+type staticTable struct {
+	base       string
+	latest     map[string]float64
+	published  time.Time
+	historical map[Date]map[string]float64
+	currencies map[string]string
+	location   *time.Location
+}
+
+// today returns the current date in the table's time zone.
+func (t *staticTable) today() Date {
+	return DateOf(time.Now().In(t.location))
+}
+
+// rates returns the table's rates for a date (the latest if zero) in base.
+func (t *staticTable) rates(base string, date Date) (*RateResponse, error) {
+	rsp := &RateResponse{}
+	rates := t.latest
+	if date.IsZero() {
+		date = t.today()
+		if !t.published.IsZero() {
+			rsp.Timestamp = t.published.Unix()
+		}
+	} else {
+		var ok bool
+		if rates, ok = t.historical[date]; !ok {
+			return nil, fmt.Errorf("%w: no rates on %s", ErrRatesNotFound, date)
+		}
+		rsp.Date = date.String()
+	}
+
+	if base == "" {
+		base = t.base
+	}
+	rsp.Base = base
+	rsp.Rates = make(map[string]float64, len(rates)+1)
+
+	// Rebase the rates, which are per the table's base, via it.
+	per := 1.0
+	if base != t.base {
+		rate, ok := rates[base]
+		if !ok || rate == 0 {
+			return nil, fmt.Errorf("%w: no rates for base %s", ErrRatesNotFound, base)
+		}
+		per = rate
+		rsp.Rates[t.base] = 1 / per
+	}
+	for code, rate := range rates {
+		rsp.Rates[code] = rate / per
+	}
+	return applyFixedRates(rsp, date, false), nil
+}
+
+// StaticRates serves the latest rates from a RateTable.
+type StaticRates struct {
+	table        *staticTable
+	mu           sync.RWMutex
+	baseCurrency string
+}
+
+// List will return all the latest rates for the base currency.
+func (s *StaticRates) List() (*RateResponse, error) {
+	return s.table.rates(s.GetBaseCurrency(), Date{})
+}
+
+// Get will return a single latest rate for a given currency.
+func (s *StaticRates) Get(code string) (*float64, error) {
+	if code == "" {
+		return nil, errors.New("currency code must be passed")
+	}
+	rsp, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return lookupRate(rsp, code, s.table.today(), nil)
+}
+
+// Convert will convert an amount between two currencies using the latest rates.
+func (s *StaticRates) Convert(amount float64, from, to string) (float64, error) {
+	return convert(amount, from, to, s.table.today(), s.Get)
+}
+
+// GetBaseCurrency will return the baseCurrency.
+func (s *StaticRates) GetBaseCurrency() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baseCurrency
+}
+
+// SetBaseCurrency will set the base currency rates are returned in.
+func (s *StaticRates) SetBaseCurrency(base string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseCurrency = base
+}
+
+// StaticHistoricalRates serves rates for past dates from a RateTable.
+type StaticHistoricalRates struct {
+	table        *staticTable
+	mu           sync.RWMutex
+	baseCurrency string
+}
+
+// List will return all the rates for the base currency for the day the given
+// time falls on in the table's Location.
+func (s *StaticHistoricalRates) List(date time.Time) (*RateResponse, error) {
+	return s.ListOn(DateOf(date.In(s.table.location)))
+}
+
+// ListOn is like List, but for a calendar date.
+func (s *StaticHistoricalRates) ListOn(date Date) (*RateResponse, error) {
+	if date.IsZero() {
+		return nil, fmt.Errorf("%w: no rates on %s", ErrRatesNotFound, date)
+	}
+	return s.table.rates(s.GetBaseCurrency(), date)
+}
+
+// Get will return a single rate for a given currency on a given day.
+func (s *StaticHistoricalRates) Get(code string, date time.Time) (*float64, error) {
+	return s.GetOn(code, DateOf(date.In(s.table.location)))
+}
+
+// GetOn is like Get, but for a calendar date.
+func (s *StaticHistoricalRates) GetOn(code string, date Date) (*float64, error) {
+	if code == "" {
+		return nil, errors.New("currency code must be passed")
+	}
+	rsp, err := s.ListOn(date)
+	if err != nil {
+		return nil, err
+	}
+	return lookupRate(rsp, code, date, nil)
+}
+
+// Convert will convert an amount between two currencies using the rates for the given date.
+func (s *StaticHistoricalRates) Convert(amount float64, from, to string, date time.Time) (float64, error) {
+	return s.ConvertOn(amount, from, to, DateOf(date.In(s.table.location)))
+}
+
+// ConvertOn is like Convert, but for a calendar date.
+func (s *StaticHistoricalRates) ConvertOn(amount float64, from, to string, date Date) (float64, error) {
+	return convert(amount, from, to, date, func(code string) (*float64, error) {
+		return s.GetOn(code, date)
+	})
+}
+
+// GetBaseCurrency will return the baseCurrency.
+func (s *StaticHistoricalRates) GetBaseCurrency() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baseCurrency
+}
+
+// SetBaseCurrency will set the base currency rates are returned in.
+func (s *StaticHistoricalRates) SetBaseCurrency(base string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseCurrency = base
+}
+
+// StaticCurrencies lists the currencies in a RateTable.
+type StaticCurrencies struct {
+	table *staticTable
+}
+
+// List will return the table's currencies, sorted by code.
+func (s *StaticCurrencies) List() ([]*CurrencyResponse, error) {
+	list := make([]*CurrencyResponse, 0, len(s.table.currencies))
+	for code, name := range s.table.currencies {
+		list = append(list, &CurrencyResponse{Code: code, Name: name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list, nil
+}
+
+// copyRates returns a copy of a rate table.
+func copyRates(rates map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		out[code] = rate
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package dinero
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestStatic will test serving rates and currencies from a static rate table.
+func TestStatic(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	static := NewStatic(RateTable{
+		Latest:     map[string]float64{"AUD": 1.25, "NZD": 1.5, "EUR": 0.8},
+		Published:  time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC),
+		Historical: map[Date]map[string]float64{NewDate(2021, 1, 1): {"AUD": 1.3, "NZD": 1.4}},
+		Currencies: map[string]string{"NZD": "New Zealand Dollar", "AUD": "Australian Dollar"},
+	})
+
+	// Latest rates, in any base the table has a rate for.
+	var rates RatesProvider = static.Rates
+	rsp, err := rates.List()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Base).Should(Equal("USD"))
+	Expect(rsp.PublishedAt()).Should(Equal(time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	rate, err := rates.Get("USD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.0))
+
+	rates.SetBaseCurrency("AUD")
+	rate, err = rates.Get("USD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(0.8))
+	rate, err = rates.Get("NZD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.2))
+
+	_, err = rates.Get("XYZ")
+	Expect(err).Should(Equal(ErrRatesNotFound))
+	rates.SetBaseCurrency("XYZ")
+	_, err = rates.List()
+	Expect(errors.Is(err, ErrRatesNotFound)).Should(BeTrue())
+	rates.SetBaseCurrency("")
+
+	// Fixed rates apply, as they do to rates from OXR.
+	converted, err := rates.Convert(100, "EUR", "DEM")
+	Expect(err).Should(BeNil())
+	Expect(converted).Should(BeNumerically("~", 195.583, 1e-9))
+	converted, err = rates.Convert(100, "AUD", "NZD")
+	Expect(err).Should(BeNil())
+	Expect(converted).Should(Equal(120.0))
+
+	// Historical rates, only for the dates in the table.
+	var historical HistoricalRatesProvider = static.HistoricalRates
+	rate, err = historical.GetOn("AUD", NewDate(2021, 1, 1))
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.3))
+	rate, err = historical.Get("NZD", time.Date(2021, 1, 1, 23, 0, 0, 0, time.UTC))
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.4))
+	_, err = historical.GetOn("AUD", NewDate(2021, 1, 2))
+	Expect(errors.Is(err, ErrRatesNotFound)).Should(BeTrue())
+
+	// Currencies.
+	var currencies CurrencyLister = static.Currencies
+	list, err := currencies.List()
+	Expect(err).Should(BeNil())
+	Expect(list).Should(Equal([]*CurrencyResponse{
+		{Code: "AUD", Name: "Australian Dollar"},
+		{Code: "NZD", Name: "New Zealand Dollar"},
+	}))
+}
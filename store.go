@@ -0,0 +1,335 @@
+package dinero
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotStored is returned by an offline client when the rates requested
+	// aren't held in its store.
+	ErrNotStored = errors.New("rates not available offline")
+)
+
+// RateStore persists rate tables beyond the lifetime of the in-memory cache,
+// keyed by base currency and date.
+type RateStore interface {
+	// Load returns the rates stored for base on date, or ErrNotStored.
+	Load(base string, date Date) (*RateResponse, error)
+	// Save stores the rates for base on date, replacing any already stored.
+	Save(base string, date Date, rsp *RateResponse) error
+	// Dates returns the dates rates are stored for base, in ascending order.
+	Dates(base string) ([]Date, error)
+}
+
+// StoredRates is the serialised form of a single rate table in a RateStore.
+type StoredRates struct {
+	Base     string        `json:"base"`
+	Date     string        `json:"date"`
+	Response *RateResponse `json:"response"`
+}
+
+// Snapshot is the serialised form of a FileStore.
+type Snapshot struct {
+	UpdatedAt time.Time      `json:"updated_at"`
+	Records   []*StoredRates `json:"records"`
+}
+
+// FileStore is a RateStore held in a single JSON snapshot file, which is
+// rewritten on every save. Saving many rate tables, e.g. syncing or
+// backfilling a range of days, should be done within Batch so the file is
+// written once; for large histories, use a DirStore, which writes each rate
+// table to its own file.
+type FileStore struct {
+	mu       sync.RWMutex
+	path     string
+	snapshot *Snapshot
+	// batches is the number of Batch calls in progress, and dirty whether
+	// they've saved anything yet to be written.
+	batches int
+	dirty   bool
+}
+
+// NewFileStore opens the snapshot file at path, which is created on the
+// first save if it doesn't exist.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:     path,
+		snapshot: &Snapshot{},
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s.snapshot); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load returns the rates stored for base on date, or ErrNotStored.
+func (s *FileStore) Load(base string, date Date) (*RateResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day := date.String()
+	for i := len(s.snapshot.Records) - 1; i >= 0; i-- {
+		record := s.snapshot.Records[i]
+		if record.Base == base && record.Date == day && record.Response != nil {
+			return record.Response.clone(), nil
+		}
+	}
+	return nil, ErrNotStored
+}
+
+// Save stores the rates for base on date and rewrites the snapshot file,
+// unless within Batch.
+func (s *FileStore) Save(base string, date Date, rsp *RateResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := &StoredRates{
+		Base:     base,
+		Date:     date.String(),
+		Response: rsp.clone(),
+	}
+
+	replaced := false
+	for i, existing := range s.snapshot.Records {
+		if existing.Base == record.Base && existing.Date == record.Date {
+			s.snapshot.Records[i] = record
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.snapshot.Records = append(s.snapshot.Records, record)
+	}
+	s.snapshot.UpdatedAt = time.Now().UTC()
+
+	if s.batches > 0 {
+		s.dirty = true
+		return nil
+	}
+	return s.write()
+}
+
+// Batch will call fn, holding any rates saved meanwhile in memory and
+// writing the snapshot file once it returns, even if it fails. Batches may
+// be nested; the file is written when the outermost returns.
+func (s *FileStore) Batch(fn func() error) error {
+	s.mu.Lock()
+	s.batches++
+	s.mu.Unlock()
+
+	err := fn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches--
+	if s.batches > 0 || !s.dirty {
+		return err
+	}
+	s.dirty = false
+	if werr := s.write(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// Dates returns the dates rates are stored for base, in ascending order.
+func (s *FileStore) Dates(base string) ([]Date, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dates := []Date{}
+	for _, record := range s.snapshot.Records {
+		if record.Base != base {
+			continue
+		}
+		date, err := ParseDate(record.Date)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates, nil
+}
+
+// UpdatedAt returns when the snapshot was last saved to.
+func (s *FileStore) UpdatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot.UpdatedAt
+}
+
+// write replaces the snapshot file.
+func (s *FileStore) write() error {
+	data, err := json.Marshal(s.snapshot)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return writeFile(s.path, data)
+}
+
+// writeFile replaces the file at path with data, via a temporary file so a
+// failed write never leaves a partial file behind.
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DirStore is a RateStore held in a directory, with each rate table in its
+// own JSON file at BASE/YYYY-MM-DD.json. Records use the same serialisation as
+// a FileStore snapshot.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore opens the store in dir, creating the directory if needed.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// Load returns the rates stored for base on date, or ErrNotStored.
+func (s *DirStore) Load(base string, date Date) (*RateResponse, error) {
+	data, err := ioutil.ReadFile(s.path(base, date))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotStored
+		}
+		return nil, err
+	}
+
+	record := &StoredRates{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	if record.Response == nil {
+		return nil, ErrNotStored
+	}
+	return record.Response, nil
+}
+
+// Save stores the rates for base on date, replacing any already stored.
+func (s *DirStore) Save(base string, date Date, rsp *RateResponse) error {
+	data, err := json.Marshal(&StoredRates{
+		Base:     base,
+		Date:     date.String(),
+		Response: rsp,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := s.path(base, date)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// Dates returns the dates rates are stored for base, in ascending order.
+func (s *DirStore) Dates(base string) ([]Date, error) {
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, base))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Date{}, nil
+		}
+		return nil, err
+	}
+
+	dates := []Date{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		date, err := ParseDate(name[:len(name)-len(".json")])
+		if err != nil {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates, nil
+}
+
+func (s *DirStore) path(base string, date Date) string {
+	return filepath.Join(s.dir, base, date.String()+".json")
+}
+
+// latestStored returns the most recent rates held in store for base.
+func latestStored(store RateStore, base string) (*RateResponse, Date, error) {
+	dates, err := store.Dates(base)
+	if err != nil {
+		return nil, Date{}, err
+	}
+	if len(dates) == 0 {
+		return nil, Date{}, ErrNotStored
+	}
+
+	date := dates[len(dates)-1]
+	rsp, err := store.Load(base, date)
+	return rsp, date, err
+}
+
+// persist saves rsp to the client's store, if it has one.
+func (c *Client) persist(rsp *RateResponse, date Date) error {
+	if c.Store == nil {
+		return nil
+	}
+	return c.Store.Save(rsp.Base, date, rsp)
+}
+
+// loadStored returns the rates held in the client's store for base on date,
+// or the most recent rates held if date is zero.
+func (c *Client) loadStored(base string, date Date) (*RateResponse, error) {
+	if c.Store == nil {
+		return nil, ErrNotStored
+	}
+	if base == "" {
+		base = defaultBaseCurrency
+	}
+	if date.IsZero() {
+		rsp, _, err := latestStored(c.Store, base)
+		return rsp, err
+	}
+	return c.Store.Load(base, date)
+}
@@ -0,0 +1,140 @@
+package dinero
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestFileStore will test saving and loading rates through a snapshot file.
+func TestFileStore(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "rates", "snapshot.json")
+	store, err := NewFileStore(path)
+	Expect(err).Should(BeNil())
+
+	first := NewDate(2021, 1, 2)
+	second := NewDate(2021, 1, 1)
+	Expect(store.Save("USD", first, &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.3}})).Should(BeNil())
+	Expect(store.Save("USD", second, &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.2}})).Should(BeNil())
+	Expect(store.Save("USD", first, &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.25}})).Should(BeNil())
+
+	// Re-open the snapshot from disk.
+	store, err = NewFileStore(path)
+	Expect(err).Should(BeNil())
+	Expect(store.UpdatedAt()).ShouldNot(BeZero())
+
+	dates, err := store.Dates("USD")
+	Expect(err).Should(BeNil())
+	Expect(dates).Should(Equal([]Date{second, first}))
+
+	rsp, err := store.Load("USD", first)
+	Expect(err).Should(BeNil())
+	Expect(rsp.Rates).Should(HaveKeyWithValue("AUD", 1.25))
+
+	_, err = store.Load("AUD", first)
+	Expect(errors.Is(err, ErrNotStored)).Should(BeTrue())
+}
+
+// TestFileStore_Batch will test saving many rate tables with a single write.
+func TestFileStore_Batch(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	store, err := NewFileStore(path)
+	Expect(err).Should(BeNil())
+
+	failed := errors.New("failed")
+	err = store.Batch(func() error {
+		for day := 1; day <= 3; day++ {
+			Expect(store.Save("USD", NewDate(2021, 1, day), &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.3}})).Should(BeNil())
+		}
+
+		// Nothing is written until the batch is done.
+		_, err := os.Stat(path)
+		Expect(errors.Is(err, os.ErrNotExist)).Should(BeTrue())
+		return failed
+	})
+	Expect(err).Should(Equal(failed))
+
+	// What was saved is written, even if the batch failed.
+	store, err = NewFileStore(path)
+	Expect(err).Should(BeNil())
+	dates, err := store.Dates("USD")
+	Expect(err).Should(BeNil())
+	Expect(dates).Should(HaveLen(3))
+}
+
+// TestFileStore_Client will test rates are written through to the store and served from it offline.
+func TestFileStore_Client(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "snapshot.json"))
+	Expect(err).Should(BeNil())
+
+	// Init dinero client.
+	client := newTestClient(t, "USD", ratesHandler(map[string]float64{
+		"AUD": 1.35,
+	}))
+	client.Store = store
+
+	date := NewDate(2021, 1, 1)
+	_, err = client.Rates.List()
+	Expect(err).Should(BeNil())
+	_, err = client.HistoricalRates.ListOn(date)
+	Expect(err).Should(BeNil())
+
+	// A fresh offline client serves the stored rates.
+	offline := NewClient("", "USD", 1*time.Minute)
+	offline.BackendURL = nil
+	offline.Store = store
+	offline.Offline = true
+
+	rate, err := offline.Rates.Get("AUD")
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.35))
+
+	rate, err = offline.HistoricalRates.GetOn("AUD", date)
+	Expect(err).Should(BeNil())
+	Expect(*rate).Should(Equal(1.35))
+
+	_, err = offline.HistoricalRates.GetOn("AUD", date.AddDays(1))
+	Expect(errors.Is(err, ErrNotStored)).Should(BeTrue())
+}
+
+// TestDirStore will test saving and loading rates through a directory of files.
+func TestDirStore(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	store, err := NewDirStore(filepath.Join(t.TempDir(), "rates"))
+	Expect(err).Should(BeNil())
+
+	dates, err := store.Dates("USD")
+	Expect(err).Should(BeNil())
+	Expect(dates).Should(BeEmpty())
+
+	first := NewDate(2021, 1, 2)
+	second := NewDate(2021, 1, 1)
+	Expect(store.Save("USD", first, &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.3}})).Should(BeNil())
+	Expect(store.Save("USD", second, &RateResponse{Base: "USD", Rates: map[string]float64{"AUD": 1.2}})).Should(BeNil())
+
+	dates, err = store.Dates("USD")
+	Expect(err).Should(BeNil())
+	Expect(dates).Should(Equal([]Date{second, first}))
+
+	rsp, err := store.Load("USD", second)
+	Expect(err).Should(BeNil())
+	Expect(rsp.Rates).Should(HaveKeyWithValue("AUD", 1.2))
+
+	_, err = store.Load("USD", first.AddDays(1))
+	Expect(errors.Is(err, ErrNotStored)).Should(BeTrue())
+}
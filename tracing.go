@@ -0,0 +1,83 @@
+package dinero
+
+import (
+	"context"
+	"errors"
+)
+
+// Tracer starts spans around the client's API calls, cache lookups and HTTP
+// requests. It's small enough to adapt any tracing library to, e.g.
+// OpenTelemetry's trace.Tracer; spans must be started as children of any
+// span in the context passed, and the returned context must carry the new
+// span.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttributes sets attributes on the span.
+	SetAttributes(attributes ...Attribute)
+	// RecordError records an error, and marks the span as failed.
+	RecordError(err error)
+	// End ends the span.
+	End()
+}
+
+// Attribute is a span attribute. Value is a string, bool or int.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// The attributes set on spans. The app ID is never recorded.
+const (
+	AttributeBase       = "dinero.base"
+	AttributeDate       = "dinero.date"
+	AttributeSymbols    = "dinero.symbols"
+	AttributeCacheHit   = "dinero.cache.hit"
+	AttributeEndpoint   = "dinero.endpoint"
+	AttributeErrorCode  = "dinero.error_code"
+	AttributeHTTPMethod = "http.method"
+	AttributeHTTPStatus = "http.status_code"
+)
+
+// noopSpan is the span used when the client has no Tracer.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// startSpan starts a span with the client's Tracer, if it has one.
+func (c *Client) startSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	if c.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := c.Tracer.Start(ctx, name)
+	if len(attributes) > 0 {
+		span.SetAttributes(attributes...)
+	}
+	return ctx, span
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span Span, err error) {
+	if err != nil {
+		var rsp *ErrorResponse
+		if errors.As(err, &rsp) && rsp.Message != "" {
+			span.SetAttributes(Attribute{Key: AttributeErrorCode, Value: rsp.Message})
+		}
+		span.RecordError(redactError(err))
+	}
+	span.End()
+}
+
+// baseAttribute returns the attribute for a base currency, which OXR takes
+// to be USD if none is passed.
+func baseAttribute(base string) Attribute {
+	if base == "" {
+		base = defaultBaseCurrency
+	}
+	return Attribute{Key: AttributeBase, Value: base}
+}
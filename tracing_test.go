@@ -0,0 +1,118 @@
+package dinero
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattevans/dinero/dinerotest"
+	. "github.com/onsi/gomega"
+)
+
+// recordedSpan is a span recorded by testTracer.
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	errors     []error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...Attribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.errors = append(s.errors, err) }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+type spanKey struct{}
+
+// testTracer records the spans it starts.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// TestTracing will test tracing API calls, cache lookups and requests.
+func TestTracing(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	server := dinerotest.NewServer()
+	defer server.Close()
+	server.SetLatest(map[string]float64{"AUD": 1.25}, time.Time{})
+
+	tracer := &testTracer{}
+	client := NewClient("secret-app-id", "", 1*time.Minute)
+	client.BackendURL, _ = url.Parse(server.URL)
+	client.Tracer = tracer
+
+	ctx, root := tracer.Start(context.Background(), "checkout")
+	_, err := client.Rates.GetContext(ctx, "AUD")
+	Expect(err).Should(BeNil())
+
+	names := []string{}
+	for _, span := range tracer.spans {
+		Expect(span.ended || span == root).Should(BeTrue())
+		names = append(names, span.name)
+	}
+	Expect(names).Should(Equal([]string{"checkout", "dinero.Rates.Get", "dinero.Cache.Get", "dinero.Do", "dinero.Cache.Get"}))
+
+	get := tracer.spans[1]
+	Expect(get.parent).Should(Equal(root))
+	Expect(get.attributes).Should(Equal(map[string]interface{}{AttributeBase: "USD", AttributeSymbols: "AUD"}))
+	Expect(tracer.spans[2].parent).Should(Equal(get))
+	Expect(tracer.spans[2].attributes[AttributeCacheHit]).Should(Equal(false))
+	Expect(tracer.spans[4].attributes[AttributeCacheHit]).Should(Equal(true))
+
+	do := tracer.spans[3]
+	Expect(do.parent).Should(Equal(get))
+	Expect(do.attributes).Should(Equal(map[string]interface{}{
+		AttributeHTTPMethod: "GET",
+		AttributeEndpoint:   "latest",
+		AttributeHTTPStatus: 200,
+	}))
+
+	// OXR error codes are recorded.
+	tracer.spans = nil
+	server.Fail(dinerotest.Historical, dinerotest.ErrNotAvailable)
+	_, err = client.HistoricalRates.ListOnContext(context.Background(), NewDate(2021, 1, 1))
+	Expect(err).ShouldNot(BeNil())
+	list := tracer.spans[0]
+	Expect(list.name).Should(Equal("dinero.HistoricalRates.List"))
+	Expect(list.attributes[AttributeDate]).Should(Equal("2021-01-01"))
+	Expect(list.attributes[AttributeErrorCode]).Should(Equal("not_available"))
+	Expect(list.errors).Should(HaveLen(1))
+
+	// The app ID is never recorded, even in errors.
+	tracer.spans = nil
+	server.Close()
+	_, err = client.Currencies.ListContext(context.Background())
+	Expect(err).ShouldNot(BeNil())
+	_, err = client.HistoricalRates.ListOnContext(context.Background(), NewDate(2021, 1, 2))
+	Expect(err).ShouldNot(BeNil())
+	Expect(err.Error()).ShouldNot(ContainSubstring("secret-app-id"))
+	for _, span := range tracer.spans {
+		Expect(fmt.Sprint(span.attributes)).ShouldNot(ContainSubstring("secret-app-id"))
+		for _, err := range span.errors {
+			Expect(strings.Contains(err.Error(), "secret-app-id")).Should(BeFalse())
+		}
+	}
+	Expect(tracer.spans[len(tracer.spans)-1].errors).ShouldNot(BeEmpty())
+}
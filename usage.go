@@ -0,0 +1,130 @@
+package dinero
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+const (
+	usageAPIPath = "usage.json"
+	// usageEndpoint is the endpoint usage.json is reported as.
+	usageEndpoint = "usage"
+)
+
+// UsageService handles usage request/responses.
+type UsageService struct {
+	client *Client
+}
+
+// NewUsageService creates a new handler for this service.
+func NewUsageService(
+	client *Client,
+) *UsageService {
+	return &UsageService{
+		client: client,
+	}
+}
+
+// UsageResponse holds the plan and usage statistics for an app ID.
+type UsageResponse struct {
+	Status int64     `json:"status"`
+	Data   UsageData `json:"data"`
+}
+
+// UsageData holds the details of an app ID's plan and usage.
+type UsageData struct {
+	AppID  string    `json:"app_id"`
+	Status string    `json:"status"`
+	Plan   UsagePlan `json:"plan"`
+	Usage  Usage     `json:"usage"`
+}
+
+// UsagePlan describes the OXR plan an app ID is on.
+type UsagePlan struct {
+	Name            string          `json:"name"`
+	Quota           string          `json:"quota"`
+	UpdateFrequency string          `json:"update_frequency"`
+	Features        map[string]bool `json:"features"`
+}
+
+// Usage holds the request counts for the current billing period. A negative
+// RequestsQuota means the plan is unlimited.
+type Usage struct {
+	Requests          int64 `json:"requests"`
+	RequestsQuota     int64 `json:"requests_quota"`
+	RequestsRemaining int64 `json:"requests_remaining"`
+	DaysElapsed       int64 `json:"days_elapsed"`
+	DaysRemaining     int64 `json:"days_remaining"`
+	DailyAverage      int64 `json:"daily_average"`
+}
+
+// Unlimited reports whether the plan has no request quota.
+func (u Usage) Unlimited() bool {
+	return u.RequestsQuota < 0
+}
+
+// resets returns when the billing period ends, taken to be the start (in UTC)
+// of the day after its last.
+func (u Usage) resets(now time.Time) time.Time {
+	day := DateOf(now.UTC()).AddDays(int(u.DaysRemaining) + 1)
+	return time.Date(day.Year, day.Month, day.Day, 0, 0, 0, 0, time.UTC)
+}
+
+// Get will fetch the plan and usage statistics for the client's app ID. These
+// are never cached.
+func (s *UsageService) Get() (*UsageResponse, error) {
+	// Build request.
+	req, err := s.client.NewRequest(
+		"GET",
+		usageAPIPath,
+		url.Values{},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make request.
+	rsp := &UsageResponse{}
+	if _, err = s.client.Do(req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// GetFor is like Get, but for the given app ID rather than the client's.
+func (s *UsageService) GetFor(appID string) (*UsageResponse, error) {
+	req, err := s.client.newRequest(
+		"GET",
+		usageAPIPath,
+		url.Values{},
+		appID,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &UsageResponse{}
+	if _, err = s.client.Do(req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// RefreshKeys will fetch the usage of every app ID in the client's KeyPool,
+// updating what the pool knows of their quotas. It stops at the first
+// error other than a key being rejected.
+func (s *UsageService) RefreshKeys() error {
+	pool, ok := s.client.Keys.(*KeyPool)
+	if !ok {
+		return errors.New("client has no key pool")
+	}
+	for _, key := range pool.secrets() {
+		if _, err := s.GetFor(key); err != nil && !isInvalidKey(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package dinero
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestUsage_Get will test fetching plan and usage statistics.
+func TestUsage_Get(t *testing.T) {
+	// Register the test.
+	RegisterTestingT(t)
+
+	// Init dinero client.
+	client := newTestClient(t, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.URL.Path).Should(Equal("/api/usage.json"))
+		Expect(r.URL.Query().Get("app_id")).Should(Equal("12345"))
+		_, _ = w.Write([]byte(`{
+			"status": 200,
+			"data": {
+				"app_id": "12345",
+				"status": "active",
+				"plan": {"name": "Developer", "quota": "10,000 requests / month", "update_frequency": "3600s", "features": {"base": true}},
+				"usage": {"requests": 2500, "requests_quota": 10000, "requests_remaining": 7500, "days_elapsed": 10, "days_remaining": 20, "daily_average": 250}
+			}
+		}`))
+	}))
+
+	rsp, err := client.Usage.Get()
+	Expect(err).Should(BeNil())
+	Expect(rsp.Data.Plan.Name).Should(Equal("Developer"))
+	Expect(rsp.Data.Usage.RequestsRemaining).Should(Equal(int64(7500)))
+	Expect(rsp.Data.Usage.Unlimited()).Should(BeFalse())
+}